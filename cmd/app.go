@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/rkgcloud/crud/pkg/config"
+	"github.com/rkgcloud/crud/pkg/database"
+	"github.com/rkgcloud/crud/pkg/logredact"
+	"github.com/rkgcloud/crud/pkg/logship"
+	"github.com/rkgcloud/crud/pkg/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// app holds what every command shares
+type app struct {
+	// flags returns the configuration flags passed on the command line
+	flags func() config.Flags
+}
+
+// loadConfig loads the configuration, letting command-line flags override the
+// environment, and returns it with the flags it was loaded with. Log output is
+// masked as configured from then on.
+func (a *app) loadConfig() (*config.Config, config.Flags, error) {
+	flags := a.flags()
+	cfg, err := config.Load(flags)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	redactLogs(cfg.LogRedact, nil)
+	return cfg, flags, nil
+}
+
+// redactLogs masks the configured fields in the output of the log and slog
+// packages and of gin's request log. The masked output is also shipped to
+// sink when it is set.
+func redactLogs(cfg config.LogRedactConfig, sink *logship.Shipper) {
+	redactor := logredact.New(cfg)
+	var app, access, errs io.Writer = os.Stderr, os.Stdout, os.Stderr
+	if sink != nil {
+		app = io.MultiWriter(app, sink.Writer("app"))
+		access = io.MultiWriter(access, sink.Writer("access"))
+		errs = io.MultiWriter(errs, sink.Writer("error"))
+	}
+	log.SetOutput(redactor.Writer(app))
+	gin.DefaultWriter = redactor.Writer(access)
+	gin.DefaultErrorWriter = redactor.Writer(errs)
+}
+
+// openDB connects to the configured database
+func (a *app) openDB(cfg *config.Config) (*gorm.DB, error) {
+	db, err := database.ConnectDB(cfg.DatabaseURL, cfg.DatabaseLog)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	return db, nil
+}
+
+// schema lists the models stored in the database
+var schema = []any{&models.User{}, &models.Address{}, &models.Invitation{}, &models.OutboxEvent{}, &models.ExportJob{}, &models.AuditEntry{}, &models.Tag{}}
+
+// migrate creates and updates the tables of every model
+func migrate(db *gorm.DB) error {
+	if err := db.AutoMigrate(schema...); err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+	if err := normalizeEmails(db); err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+	return nil
+}
+
+// emailTables are the tables whose email column is stored trimmed and lowercased
+var emailTables = []string{"users", "invitations"}
+
+// normalizeEmails trims and lowercases the email addresses written before
+// they were normalized on write, so lookups and the unique indexes see one
+// spelling. When addresses would collide, nothing is changed and they are
+// reported for an operator to merge or remove.
+func normalizeEmails(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, table := range emailTables {
+			var collisions []string
+			err := tx.Raw("SELECT lower(trim(email)) FROM ? GROUP BY 1 HAVING count(*) > 1 ORDER BY 1", clause.Table{Name: table}).
+				Scan(&collisions).Error
+			if err != nil {
+				return err
+			}
+			if len(collisions) > 0 {
+				return fmt.Errorf("%s holds email addresses differing only in case or spacing, resolve them and migrate again: %s",
+					table, strings.Join(collisions, ", "))
+			}
+			err = tx.Exec("UPDATE ? SET email = lower(trim(email)) WHERE email <> lower(trim(email))", clause.Table{Name: table}).Error
+			if err != nil {
+				return fmt.Errorf("normalizing the emails of %s: %w", table, err)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/rkgcloud/crud/pkg/audit"
+
+	"github.com/spf13/cobra"
+)
+
+// newAuditCommand creates the command grouping the audit log commands
+func newAuditCommand(a *app) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Inspect the audit log",
+		Args:  cobra.NoArgs,
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "verify",
+		Short: "Check the audit log hash chain for modified or deleted entries",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, _, err := a.loadConfig()
+			if err != nil {
+				return err
+			}
+			db, err := a.openDB(cfg)
+			if err != nil {
+				return err
+			}
+			report, err := audit.Verify(cmd.Context(), db)
+			if err != nil {
+				return fmt.Errorf("failed to verify audit log: %w", err)
+			}
+
+			out := cmd.OutOrStdout()
+			fmt.Fprintf(out, "%d entries verified, %d recorded before chaining, %d erased\n", report.Entries, report.Unchained, report.Erased)
+			fmt.Fprintf(out, "head %s\n", report.Head)
+			for _, p := range report.Problems {
+				fmt.Fprintf(out, "entry %d: %s\n", p.ID, p.Reason)
+			}
+			if !report.Valid() {
+				return errors.New("audit log failed verification")
+			}
+			return nil
+		},
+	})
+	return cmd
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/rkgcloud/crud/pkg/audit"
+	"github.com/rkgcloud/crud/pkg/models"
+	"github.com/rkgcloud/crud/pkg/outbox"
+	"github.com/rkgcloud/crud/pkg/validation"
+
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
+
+// newCreateAdminCommand creates the command bootstrapping an administrator
+func newCreateAdminCommand(a *app) *cobra.Command {
+	var email, name, role string
+	cmd := &cobra.Command{
+		Use:   "createadmin",
+		Short: "Create an administrator, or grant the role to an existing user",
+		Long: "Create an administrator, or grant the role to an existing user.\n" +
+			"A missing --email, and --name when the user is created, are read from standard input.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if role != models.RoleAdmin && role != models.RoleUser {
+				return fmt.Errorf("--role must be %s or %s", models.RoleAdmin, models.RoleUser)
+			}
+			in := bufio.NewReader(cmd.InOrStdin())
+			var err error
+			if email, err = prompt(cmd, in, "Email", email); err != nil {
+				return err
+			}
+			if email, err = validation.NormalizeEmail(email); err != nil {
+				return fmt.Errorf("invalid email: %w", err)
+			}
+
+			cfg, _, err := a.loadConfig()
+			if err != nil {
+				return err
+			}
+			db, err := a.openDB(cfg)
+			if err != nil {
+				return err
+			}
+			// A fresh deployment may not have been migrated yet
+			if err := migrate(db); err != nil {
+				return err
+			}
+			// The name is only needed to create the user
+			var existing int64
+			if err := db.Model(&models.User{}).Where("email = ?", email).Count(&existing).Error; err != nil {
+				return err
+			}
+			if existing == 0 {
+				if name, err = prompt(cmd, in, "Name", name); err != nil {
+					return err
+				}
+			}
+			user, created, err := createAdmin(db, email, name, role)
+			if err != nil {
+				return err
+			}
+			if created {
+				log.Printf("Created %s %s (id %d)\n", role, user.Email, user.ID)
+			} else {
+				log.Printf("Granted %s to existing user %s (id %d)\n", role, user.Email, user.ID)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&email, "email", "", "email address of the user")
+	cmd.Flags().StringVar(&name, "name", "", "name of the user, when creating it")
+	cmd.Flags().StringVar(&role, "role", models.RoleAdmin, "role to grant")
+	return cmd
+}
+
+// createAdmin creates a verified user with role, or sets the role of the user
+// already registered with email. It reports whether the user was created.
+func createAdmin(db *gorm.DB, email, name, role string) (models.User, bool, error) {
+	var user models.User
+	created := false
+	err := db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("email = ?", email).First(&user).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if name == "" {
+				return errors.New("--name is required to create the user")
+			}
+			created = true
+			// The operator vouches for the address, so no verification email is sent
+			user = models.User{Name: name, Email: email, Role: role, Verified: true}
+			if err := tx.Create(&user).Error; err != nil {
+				return err
+			}
+			if err := outbox.Enqueue(tx, outbox.UserCreated, user); err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		default:
+			if err := tx.Model(&user).Update("role", role).Error; err != nil {
+				return err
+			}
+			if err := outbox.Enqueue(tx, outbox.UserUpdated, user); err != nil {
+				return err
+			}
+		}
+		return audit.Record(tx, audit.ActorCLI, "user.role_granted", fmt.Sprintf("users/%d", user.ID), map[string]any{
+			"role":    role,
+			"created": created,
+		})
+	})
+	if err != nil {
+		return user, false, fmt.Errorf("failed to create %s: %w", role, err)
+	}
+	return user, created, nil
+}
+
+// prompt returns value, reading it from in when it is empty
+func prompt(cmd *cobra.Command, in *bufio.Reader, label, value string) (string, error) {
+	if value != "" {
+		return value, nil
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "%s: ", label)
+	line, err := in.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	if line = strings.TrimSpace(line); line == "" {
+		return "", fmt.Errorf("--%s is required", strings.ToLower(label))
+	}
+	return line, nil
+}
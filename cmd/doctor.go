@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rkgcloud/crud/pkg/config"
+	"github.com/rkgcloud/crud/pkg/mail"
+	"github.com/rkgcloud/crud/pkg/storage"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
+
+// doctorTimeout bounds each check reaching another service
+const doctorTimeout = 5 * time.Second
+
+// Check outcomes
+const (
+	checkPass = "PASS"
+	checkWarn = "WARN"
+	checkFail = "FAIL"
+	checkSkip = "SKIP"
+)
+
+// checkResult is the outcome of one doctor check
+type checkResult struct {
+	name   string
+	status string
+	detail string
+}
+
+// newDoctorCommand creates the command verifying the environment before a deploy
+func newDoctorCommand(a *app) *cobra.Command {
+	return &cobra.Command{
+		Use:   "doctor",
+		Short: "Check the configuration and the services the deployment depends on",
+		Long: "Check the configuration, database connectivity and schema, email templates,\n" +
+			"the exports directory and the mail and event servers, and print a report.\n" +
+			"Exits with an error when any check fails.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			results := doctor(cmd.Context(), a)
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			failed := 0
+			for _, r := range results {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", r.status, r.name, r.detail)
+				if r.status == checkFail {
+					failed++
+				}
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d of %d checks failed", failed, len(results))
+			}
+			return nil
+		},
+	}
+}
+
+// doctor runs every check; checks needing the configuration or the database
+// are skipped when those are unavailable
+func doctor(ctx context.Context, a *app) []checkResult {
+	cfg, _, err := a.loadConfig()
+	if err != nil {
+		return []checkResult{{"configuration", checkFail, err.Error()}}
+	}
+	results := []checkResult{
+		{"configuration", checkPass, fmt.Sprintf("loaded for %s", cfg.Environment)},
+		checkSecrets(cfg),
+		checkTemplates(),
+		checkStorage(ctx, cfg),
+	}
+
+	db, err := a.openDB(cfg)
+	if err != nil {
+		results = append(results,
+			checkResult{"database", checkFail, err.Error()},
+			checkResult{"migrations", checkSkip, "database unavailable"})
+	} else {
+		results = append(results, checkResult{"database", checkPass, "connected"}, checkMigrations(db))
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	}
+
+	if cfg.Mail.Mode == config.MailModeSMTP {
+		results = append(results, checkReachable(ctx, "mail server", net.JoinHostPort(cfg.Mail.SMTPHost, strconv.Itoa(cfg.Mail.SMTPPort))))
+	} else {
+		results = append(results, checkResult{"mail server", checkSkip, "MAIL_MODE=" + cfg.Mail.Mode})
+	}
+	switch cfg.Events.Publisher {
+	case config.PublisherKafka:
+		for _, broker := range cfg.Events.KafkaBrokers {
+			results = append(results, checkReachable(ctx, "kafka broker", broker))
+		}
+	case config.PublisherNATS:
+		u, err := url.Parse(cfg.Events.NATSURL)
+		if err != nil {
+			results = append(results, checkResult{"nats server", checkFail, err.Error()})
+		} else {
+			results = append(results, checkReachable(ctx, "nats server", u.Host))
+		}
+	default:
+		results = append(results, checkResult{"event publisher", checkSkip, "EVENTS_PUBLISHER=" + cfg.Events.Publisher})
+	}
+	if cfg.RateLimit.Store == config.RateLimitStoreRedis {
+		if opts, err := redis.ParseURL(cfg.RateLimit.RedisURL); err != nil {
+			results = append(results, checkResult{"rate limit store", checkFail, err.Error()})
+		} else {
+			results = append(results, checkReachable(ctx, "rate limit store", opts.Addr))
+		}
+	} else {
+		results = append(results, checkResult{"rate limit store", checkSkip, "RATE_LIMIT_STORE=" + cfg.RateLimit.Store})
+	}
+	return results
+}
+
+// checkSecrets reports settings unfit for production, such as a weak SECRET
+func checkSecrets(cfg *config.Config) checkResult {
+	insecure := cfg.Insecure()
+	if len(insecure) == 0 {
+		return checkResult{"security", checkPass, "SECRET is strong and no insecure settings are in use"}
+	}
+	status := checkWarn
+	if cfg.Strict() {
+		status = checkFail
+	}
+	details := make([]string, 0, len(insecure))
+	for _, err := range insecure {
+		details = append(details, err.Error())
+	}
+	return checkResult{"security", status, strings.Join(details, "; ")}
+}
+
+// checkTemplates renders every email template
+func checkTemplates() checkResult {
+	if err := mail.CheckTemplates(); err != nil {
+		return checkResult{"email templates", checkFail, err.Error()}
+	}
+	return checkResult{"email templates", checkPass, "all templates render"}
+}
+
+// checkStorage verifies files can be written to and deleted from the configured storage
+func checkStorage(ctx context.Context, cfg *config.Config) checkResult {
+	store, err := storage.New(cfg.Storage, cfg.S3)
+	if err != nil {
+		return checkResult{"storage", checkFail, err.Error()}
+	}
+	ctx, cancel := context.WithTimeout(ctx, doctorTimeout)
+	defer cancel()
+	key := storage.Key("doctor", time.Now(), "check")
+	if err := store.Put(ctx, key, strings.NewReader("ok"), "text/plain"); err != nil {
+		return checkResult{"storage", checkFail, err.Error()}
+	}
+	if err := store.Delete(ctx, key); err != nil {
+		return checkResult{"storage", checkFail, err.Error()}
+	}
+	location := cfg.Storage.Dir
+	if cfg.Storage.Backend != config.StorageLocal {
+		location = cfg.Storage.Backend + " bucket " + cfg.Storage.Bucket
+	}
+	return checkResult{"storage", checkPass, location + " is writable"}
+}
+
+// checkMigrations reports tables and columns missing from the database
+func checkMigrations(db *gorm.DB) checkResult {
+	var missing []string
+	for _, model := range schema {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return checkResult{"migrations", checkFail, err.Error()}
+		}
+		table := stmt.Schema.Table
+		if !db.Migrator().HasTable(model) {
+			missing = append(missing, table)
+			continue
+		}
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName != "" && !db.Migrator().HasColumn(model, field.DBName) {
+				missing = append(missing, table+"."+field.DBName)
+			}
+		}
+	}
+	if len(missing) > 0 {
+		return checkResult{"migrations", checkFail, "run crud migrate, missing " + strings.Join(missing, ", ")}
+	}
+	return checkResult{"migrations", checkPass, "schema is up to date"}
+}
+
+// checkReachable verifies a TCP connection can be opened to address
+func checkReachable(ctx context.Context, name, address string) checkResult {
+	dialer := net.Dialer{Timeout: doctorTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return checkResult{name, checkFail, err.Error()}
+	}
+	conn.Close()
+	return checkResult{name, checkPass, address + " is reachable"}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"os"
+	"slices"
+
+	"github.com/rkgcloud/crud/pkg/config"
+	"github.com/rkgcloud/crud/pkg/fixtures"
+
+	"github.com/spf13/cobra"
+)
+
+// newExportFixturesCommand creates the command dumping the dataset as fixtures
+func newExportFixturesCommand(a *app) *cobra.Command {
+	var output string
+	cmd := &cobra.Command{
+		Use:   "export-fixtures",
+		Short: "Dump the users, addresses, invitations and tags as a fixtures file",
+		Long: "Dump the users, addresses, invitations and tags as a versioned JSON\n" +
+			"document to --output, or standard output, for import-fixtures to load\n" +
+			"into another environment.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, _, err := a.loadConfig()
+			if err != nil {
+				return err
+			}
+			db, err := a.openDB(cfg)
+			if err != nil {
+				return err
+			}
+			var w io.Writer = cmd.OutOrStdout()
+			if output != "" {
+				f, err := os.Create(output)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				w = f
+			}
+			doc, err := fixtures.Export(cmd.Context(), db, w)
+			if err != nil {
+				if output != "" {
+					os.Remove(output)
+				}
+				return fmt.Errorf("export failed: %w", err)
+			}
+			logFixtures("Exported", doc)
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", "", "file to write; defaults to standard output")
+	return cmd
+}
+
+// newImportFixturesCommand creates the command loading a fixtures file
+func newImportFixturesCommand(a *app) *cobra.Command {
+	var replace, force bool
+	cmd := &cobra.Command{
+		Use:   "import-fixtures FILE",
+		Short: "Load a fixtures file written by export-fixtures",
+		Long: "Load a fixtures file written by export-fixtures, or - for standard input,\n" +
+			"in one transaction. The tables must be empty unless --replace is given,\n" +
+			"which deletes their rows first.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, _, err := a.loadConfig()
+			if err != nil {
+				return err
+			}
+			if cfg.Environment == config.EnvProduction && !force {
+				return errors.New("refusing to import fixtures into a production database without --force")
+			}
+			var r io.Reader = cmd.InOrStdin()
+			if args[0] != "-" {
+				f, err := os.Open(args[0])
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				r = f
+			}
+			db, err := a.openDB(cfg)
+			if err != nil {
+				return err
+			}
+			if err := migrate(db); err != nil {
+				return err
+			}
+			doc, err := fixtures.Import(cmd.Context(), db, r, replace)
+			if errors.Is(err, fixtures.ErrNotEmpty) {
+				return fmt.Errorf("%w; pass --replace to delete the existing rows", err)
+			}
+			if err != nil {
+				return fmt.Errorf("import failed: %w", err)
+			}
+			logFixtures("Imported", doc)
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&replace, "replace", false, "delete the existing users, addresses, invitations and tags first")
+	cmd.Flags().BoolVar(&force, "force", false, "allow importing when ENVIRONMENT=prod")
+	return cmd
+}
+
+// logFixtures logs the row counts of a fixtures document; the document itself
+// may be written to standard output
+func logFixtures(action string, doc fixtures.Document) {
+	for _, table := range slices.Sorted(maps.Keys(doc.Tables)) {
+		log.Printf("%s %d rows of %s\n", action, len(doc.Tables[table]), table)
+	}
+}
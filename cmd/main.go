@@ -1,45 +1,45 @@
 package main
 
 import (
-	"log"
 	"os"
 
-	"github.com/rkgcloud/crud/pkg/api/handlers"
-	"github.com/rkgcloud/crud/pkg/database"
-	models "github.com/rkgcloud/crud/pkg/models"
+	"github.com/rkgcloud/crud/pkg/config"
 
-	"github.com/gin-gonic/gin"
+	"github.com/spf13/cobra"
 )
 
 func main() {
-	// Connect to database
-	db, err := database.ConnectDB()
-	if err != nil {
-		log.Fatal("Failed to connect to database:", err)
+	if err := newRootCommand().Execute(); err != nil {
+		os.Exit(1)
 	}
+}
 
-	// Run migrations
-	err = db.AutoMigrate(&models.User{})
-	if err != nil {
-		log.Fatal("Failed to migrate database:", err)
-	}
-
-	// Set up router
-	r := gin.Default()
-
-	// Define routes
-	r.POST("/users", func(c *gin.Context) { handlers.CreateUser(c, db) })
-	r.GET("/users", func(c *gin.Context) { handlers.GetUsers(c, db) })
-	r.GET("/users/:id", func(c *gin.Context) { handlers.GetUser(c, db) })
-	r.PUT("/users/:id", func(c *gin.Context) { handlers.UpdateUser(c, db) })
-	r.DELETE("/users/:id", func(c *gin.Context) { handlers.DeleteUser(c, db) })
-
-	// Run server
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
-	}
-	if err := r.Run(":" + port); err != nil {
-		log.Fatal(err)
+// newRootCommand creates the crud command. Without a subcommand it serves,
+// so existing deployments running the bare binary keep working.
+func newRootCommand() *cobra.Command {
+	a := &app{}
+	root := &cobra.Command{
+		Use:          "crud",
+		Short:        "User management service",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return serve(a)
+		},
 	}
+	// Command-line flags override the environment for every subcommand
+	a.flags = config.RegisterFlags(root.PersistentFlags())
+	root.AddCommand(
+		newServeCommand(a),
+		newMigrateCommand(a),
+		newSeedCommand(a),
+		newRoutesCommand(a),
+		newCreateAdminCommand(a),
+		newDoctorCommand(a),
+		newAuditCommand(a),
+		newBackupCommand(a),
+		newExportFixturesCommand(a),
+		newImportFixturesCommand(a),
+	)
+	return root
 }
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"log"
+
+	"github.com/spf13/cobra"
+)
+
+// newMigrateCommand creates the command migrating the database schema
+func newMigrateCommand(a *app) *cobra.Command {
+	return &cobra.Command{
+		Use:   "migrate",
+		Short: "Create or update the database tables and exit",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, _, err := a.loadConfig()
+			if err != nil {
+				return err
+			}
+			db, err := a.openDB(cfg)
+			if err != nil {
+				return err
+			}
+			if err := migrate(db); err != nil {
+				return err
+			}
+			log.Println("Database migrated")
+			return nil
+		},
+	}
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rkgcloud/crud/pkg/alert"
+	"github.com/rkgcloud/crud/pkg/api/handlers"
+	"github.com/rkgcloud/crud/pkg/api/render"
+	"github.com/rkgcloud/crud/pkg/captcha"
+	"github.com/rkgcloud/crud/pkg/config"
+	"github.com/rkgcloud/crud/pkg/database"
+	"github.com/rkgcloud/crud/pkg/debug"
+	"github.com/rkgcloud/crud/pkg/exports"
+	"github.com/rkgcloud/crud/pkg/health"
+	"github.com/rkgcloud/crud/pkg/mail"
+	"github.com/rkgcloud/crud/pkg/middleware"
+	"github.com/rkgcloud/crud/pkg/problem"
+	"github.com/rkgcloud/crud/pkg/repository"
+	"github.com/rkgcloud/crud/pkg/requestid"
+	"github.com/rkgcloud/crud/pkg/scheduler"
+	"github.com/rkgcloud/crud/pkg/shutdown"
+	"github.com/rkgcloud/crud/pkg/verification"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// services are what the routes hand to their handlers
+type services struct {
+	cfg       *config.Config
+	db        *gorm.DB
+	watcher   *config.Watcher
+	verifier  *verification.Verifier
+	inviter   *verification.Verifier
+	downloads *verification.Verifier
+	mailer    *mail.Mailer
+	exporter  *exports.Exporter
+	sched     *scheduler.Scheduler
+	// forms issues the tokens the bot guard times form submissions with
+	forms *verification.Verifier
+	// limits counts rate limited requests; nil counts in memory
+	limits middleware.Store
+	// alerts receives operational alerts; nil discards them
+	alerts *alert.Alerter
+	// background runs the work requests leave behind, which shutdown waits for
+	background *shutdown.Group
+}
+
+// newRouter creates the router serving every route of the service, and the
+// table listing those routes. Services are only used once a request is
+// handled, so listing routes can leave them unset.
+func newRouter(s services) (*gin.Engine, *debug.RouteTable) {
+	cfg, db, watcher := s.cfg, s.db, s.watcher
+	verifier, inviter, downloads := s.verifier, s.inviter, s.downloads
+	mailer, exporter, sched := s.mailer, s.exporter, s.sched
+
+	users := repository.NewUsers(db)
+	// Optionally restrict mutations to users with a verified email
+	requireVerified := handlers.RequireVerified(users, func() bool { return watcher.Current().RequireVerified })
+
+	// Set up router
+	r := gin.New()
+	// Client addresses, which rate limits are counted and exempted by, are
+	// only taken from X-Forwarded-For when the peer is a trusted proxy, so
+	// clients cannot pick their own. The networks were parsed by config.Load.
+	proxies := make([]string, 0, len(cfg.TrustedProxies))
+	for _, n := range cfg.TrustedProxies {
+		proxies = append(proxies, n.String())
+	}
+	_ = r.SetTrustedProxies(proxies)
+	table := debug.NewRouteTable(r)
+	api := routes{engine: r, group: &r.RouterGroup, table: table}
+	r.Use(middleware.Metrics(), gin.Logger(), gin.Recovery())
+	r.Use(middleware.Alerts(s.alerts, cfg.Alerts.ErrorThreshold, cfg.Alerts.ErrorWindow))
+	// Every request gets an ID, and errors are rendered as problem details
+	r.Use(requestid.Middleware(), problem.Handler())
+	r.NoRoute(func(c *gin.Context) { problem.Abort(c, http.StatusNotFound, "Route not found") })
+	cors := middleware.NewCORS(cfg.AllowedOrigins)
+	watcher.Subscribe(cors)
+	r.Use(cors.Handler())
+	if cfg.TLS.Enabled() {
+		r.Use(middleware.HSTS(cfg.TLS.HSTSMaxAge))
+	}
+
+	// Health checks; other subsystems contribute checks through checker.Register
+	checker := health.NewHealthChecker(db, cfg.Health)
+	watcher.Subscribe(checker)
+	checker.OnTransition(func(from health.Status, report health.Report) {
+		s.alerts.Alert(alert.KindReadiness, "status changed from %s to %s%s", from, report.Status, failingChecks(report))
+	})
+	api.GET("/health", checker.Health)
+	api.GET("/health/live", checker.Live)
+	api.GET("/health/ready", checker.Ready)
+	api.GET("/health/version", checker.Version)
+
+	// Health checks are answered even under overload and take no rate limit
+	// budget; the routes registered from here on are limited per client and
+	// shed once too many requests are in flight
+	limiter := middleware.NewRateLimiter(cfg.RateLimit, cfg.AdminToken, s.limits)
+	watcher.Subscribe(limiter)
+	r.Use(limiter.Handler())
+	r.Use(middleware.NewShedder(cfg.LoadShed).Handler())
+
+	// Requests are bounded by REQUEST_TIMEOUT, except the streaming ones,
+	// which last as long as the client reads or writes, unless overridden
+	timeouts := cfg.RequestTimeout
+	timeouts.Routes = map[string]time.Duration{
+		"POST /users/import.ndjson": 0,
+		"GET /exports/:id/download": 0,
+		"GET /admin/audit/verify":   0,
+		"GET /admin/backup":         0,
+	}
+	maps.Copy(timeouts.Routes, cfg.RequestTimeout.Routes)
+	r.Use(middleware.Timeout(timeouts))
+	api.timeouts = &timeouts
+
+	// Handlers take the database from the request, so statements are cancelled
+	// with it, bounded by DB_REQUEST_TIMEOUT and logged with its request and
+	// trace IDs. Backups, audit verification and imports stream for as long as
+	// the client reads or writes, and keep the unbounded request context.
+	r.Use(database.Session(db, cfg.DatabaseTimeout))
+
+	userCtl := handlers.NewUserController(users, verifier, mailer, cfg.Mail, s.background, cfg.PublicURL)
+	addressCtl := handlers.NewAddressController()
+	invitationCtl := handlers.NewInvitationController(inviter, mailer, cfg.PublicURL)
+	exportCtl := handlers.NewExportController(exporter, downloads, cfg.PublicURL)
+	erasureCtl := handlers.NewErasureController(users, exporter)
+	auditCtl := handlers.NewAuditController(db)
+	backupCtl := handlers.NewBackupController(db)
+	adminCtl := handlers.NewAdminController(watcher, sched, table)
+
+	// Signing up and accepting invitations are open to anyone and guarded
+	// against bots as configured; admins are trusted
+	botGuard := middleware.NewBotGuard(cfg.BotGuard, cfg.AdminToken, s.forms, captcha.New(cfg.BotGuard))
+	if cfg.BotGuard.MinSubmitTime > 0 {
+		api.GET("/forms/token", botGuard.FormToken)
+	}
+
+	// Define routes; the middleware listed after a handler runs before it
+	api.POST("/users", userCtl.Create, botGuard.Handler())
+	api.GET("/users", userCtl.List)
+	api.GET("/users/verify", userCtl.Verify)
+	api.GET("/users/:id", userCtl.Get)
+	api.POST("/users/:id/verification", userCtl.ResendVerification)
+	api.PUT("/users/:id", userCtl.Update, requireVerified)
+	api.DELETE("/users/:id", userCtl.Delete)
+	api.GET("/users/:id/tags", userCtl.Tags)
+	api.GET("/users/:id/addresses", addressCtl.List)
+	api.POST("/users/:id/addresses", addressCtl.Create)
+	api.GET("/users/:id/addresses/:address_id", addressCtl.Get)
+	api.PUT("/users/:id/addresses/:address_id", addressCtl.Update)
+	api.DELETE("/users/:id/addresses/:address_id", addressCtl.Delete)
+
+	api.GET("/me/limits", limiter.Limits)
+	api.GET("/invitations/accept", invitationCtl.Pending)
+	api.POST("/invitations/accept", invitationCtl.Accept, botGuard.Handler())
+	admin := api.Group("/", middleware.AdminAuth(cfg.AdminToken))
+	admin.POST("/users/import.ndjson", userCtl.Import)
+	// Suggestions search emails by prefix, so they are not offered to anyone
+	admin.GET("/users/suggest", userCtl.Suggest)
+	admin.POST("/users/:id/erase", erasureCtl.Erase)
+	admin.POST("/users/:id/tags", userCtl.AddTag)
+	admin.DELETE("/users/:id/tags/:tag", userCtl.RemoveTag)
+	admin.POST("/invitations", invitationCtl.Create)
+	admin.GET("/invitations", invitationCtl.List)
+	admin.POST("/invitations/:id/resend", invitationCtl.Resend)
+	admin.DELETE("/invitations/:id", invitationCtl.Delete)
+	admin.POST("/exports", exportCtl.Create)
+	admin.GET("/exports/:id", exportCtl.Get)
+	api.GET("/exports/:id/download", exportCtl.Download)
+	admin.GET("/admin/config", adminCtl.Config)
+	admin.GET("/admin/users/deleted", userCtl.ListDeleted)
+	admin.GET("/admin/scheduler", adminCtl.ScheduledTasks)
+	admin.GET("/admin/routes", adminCtl.Routes)
+	admin.GET("/admin/audit/verify", auditCtl.Verify)
+	admin.GET("/admin/backup", backupCtl.Download)
+	admin.mount(debug.RegisterVars)
+	admin.mount(debug.RegisterMetrics)
+
+	// Profiling endpoints are opt-in and restricted to admins or internal networks
+	if cfg.Debug.PprofEnabled {
+		api.Group("/", middleware.AdminOrAllowlist(cfg.AdminToken, cfg.Debug.PprofAllowedNets)).mount(debug.RegisterPprof)
+	}
+
+	return r, table
+}
+
+// failingChecks lists the checks of report that are not up
+func failingChecks(report health.Report) string {
+	var failing []string
+	for _, name := range slices.Sorted(maps.Keys(report.Checks)) {
+		if result := report.Checks[name]; result.Status != health.StatusUp {
+			failing = append(failing, fmt.Sprintf("\n%s is %s: %s", name, result.Status, result.Error))
+		}
+	}
+	return strings.Join(failing, "")
+}
+
+// routes registers the routes of a group and records them in table, as gin
+// only keeps the last handler of a route, which is render.Handle for most
+type routes struct {
+	engine *gin.Engine
+	group  *gin.RouterGroup
+	table  *debug.RouteTable
+	// timeouts are those of the Timeout middleware, once it is installed
+	timeouts *config.RequestTimeoutConfig
+}
+
+// Group creates a group of routes under relativePath running middleware
+// after the middleware of rs
+func (rs routes) Group(relativePath string, middleware ...gin.HandlerFunc) routes {
+	rs.group = rs.group.Group(relativePath, middleware...)
+	return rs
+}
+
+// GET registers h for GET requests to relativePath, behind middleware
+func (rs routes) GET(relativePath string, h render.HandlerFunc, middleware ...gin.HandlerFunc) {
+	rs.handle(http.MethodGet, relativePath, h, middleware)
+}
+
+// POST registers h for POST requests to relativePath, behind middleware
+func (rs routes) POST(relativePath string, h render.HandlerFunc, middleware ...gin.HandlerFunc) {
+	rs.handle(http.MethodPost, relativePath, h, middleware)
+}
+
+// PUT registers h for PUT requests to relativePath, behind middleware
+func (rs routes) PUT(relativePath string, h render.HandlerFunc, middleware ...gin.HandlerFunc) {
+	rs.handle(http.MethodPut, relativePath, h, middleware)
+}
+
+// DELETE registers h for DELETE requests to relativePath, behind middleware
+func (rs routes) DELETE(relativePath string, h render.HandlerFunc, middleware ...gin.HandlerFunc) {
+	rs.handle(http.MethodDelete, relativePath, h, middleware)
+}
+
+// mount lets register add gin handlers to the group and records the routes it added
+func (rs routes) mount(register func(*gin.RouterGroup)) {
+	known := map[string]bool{}
+	for _, info := range rs.engine.Routes() {
+		known[info.Method+" "+info.Path] = true
+	}
+	register(rs.group)
+	for _, info := range rs.engine.Routes() {
+		if !known[info.Method+" "+info.Path] {
+			rs.record(info.Method, info.Path, nil, info.HandlerFunc)
+		}
+	}
+}
+
+// handle registers h behind middleware and records the route
+func (rs routes) handle(method, relativePath string, h render.HandlerFunc, middleware []gin.HandlerFunc) {
+	rs.group.Handle(method, relativePath, append(slices.Clip(middleware), render.Handle(h))...)
+	rs.record(method, path.Join(rs.group.BasePath(), relativePath), middleware, h)
+}
+
+// record adds the route of method and fullPath to the table, with the
+// middleware of the group before its own
+func (rs routes) record(method, fullPath string, middleware []gin.HandlerFunc, handler any) {
+	var timeout time.Duration
+	if rs.timeouts != nil {
+		var ok bool
+		if timeout, ok = rs.timeouts.Routes[method+" "+fullPath]; !ok {
+			timeout = rs.timeouts.Default
+		}
+	}
+	rs.table.Record(method, fullPath, slices.Concat(rs.group.Handlers, middleware), handler, timeout)
+}
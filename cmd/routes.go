@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/rkgcloud/crud/pkg/config"
+	"github.com/rkgcloud/crud/pkg/scheduler"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/cobra"
+)
+
+// newRoutesCommand creates the command listing the routes the service exposes
+func newRoutesCommand(a *app) *cobra.Command {
+	return &cobra.Command{
+		Use:   "routes",
+		Short: "List every route with its middleware, handler and timeout",
+		Long: "List every route with its middleware, handler and timeout.\n" +
+			"Routes depending on the configuration, such as pprof, are listed as configured.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, flags, err := a.loadConfig()
+			if err != nil {
+				return err
+			}
+			// Keep gin from logging each route as it is registered
+			gin.SetMode(gin.ReleaseMode)
+			_, table := newRouter(services{cfg: cfg, watcher: config.NewWatcher(cfg, flags), sched: scheduler.New()})
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "METHOD\tPATH\tHANDLER\tTIMEOUT\tMIDDLEWARE")
+			for _, route := range table.Routes() {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", route.Method, route.Path, route.Handler, route.Timeout, strings.Join(route.Middleware, " > "))
+			}
+			return w.Flush()
+		},
+	}
+}
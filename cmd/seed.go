@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/rkgcloud/crud/pkg/config"
+	"github.com/rkgcloud/crud/pkg/models"
+
+	"github.com/spf13/cobra"
+	"gorm.io/gorm/clause"
+)
+
+// newSeedCommand creates the command filling the database with sample users
+func newSeedCommand(a *app) *cobra.Command {
+	var count int
+	var force bool
+	cmd := &cobra.Command{
+		Use:   "seed",
+		Short: "Create sample users for development",
+		Long: "Create sample users named user1@example.com, user2@example.com, ...\n" +
+			"Users that already exist are left untouched, so seeding is repeatable.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if count <= 0 {
+				return errors.New("--users must be positive")
+			}
+			cfg, _, err := a.loadConfig()
+			if err != nil {
+				return err
+			}
+			if cfg.Environment == config.EnvProduction && !force {
+				return errors.New("refusing to seed a production database without --force")
+			}
+			db, err := a.openDB(cfg)
+			if err != nil {
+				return err
+			}
+			if err := migrate(db); err != nil {
+				return err
+			}
+
+			users := make([]models.User, 0, count)
+			for i := 1; i <= count; i++ {
+				users = append(users, models.User{
+					Name:  fmt.Sprintf("Sample User %d", i),
+					Email: fmt.Sprintf("user%d@example.com", i),
+					Age:   18 + i%60,
+					Role:  models.RoleUser,
+				})
+			}
+			result := db.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(&users, 100)
+			if result.Error != nil {
+				return fmt.Errorf("failed to seed users: %w", result.Error)
+			}
+			log.Printf("Seeded %d of %d sample users\n", result.RowsAffected, count)
+			return nil
+		},
+	}
+	cmd.Flags().IntVar(&count, "users", 10, "number of sample users")
+	cmd.Flags().BoolVar(&force, "force", false, "allow seeding when ENVIRONMENT=prod")
+	return cmd
+}
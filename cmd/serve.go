@@ -0,0 +1,319 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/rkgcloud/crud/pkg/alert"
+	"github.com/rkgcloud/crud/pkg/config"
+	"github.com/rkgcloud/crud/pkg/database"
+	"github.com/rkgcloud/crud/pkg/events"
+	"github.com/rkgcloud/crud/pkg/exports"
+	"github.com/rkgcloud/crud/pkg/logship"
+	"github.com/rkgcloud/crud/pkg/mail"
+	"github.com/rkgcloud/crud/pkg/middleware"
+	"github.com/rkgcloud/crud/pkg/models"
+	"github.com/rkgcloud/crud/pkg/outbox"
+	"github.com/rkgcloud/crud/pkg/scheduler"
+	"github.com/rkgcloud/crud/pkg/server"
+	"github.com/rkgcloud/crud/pkg/shutdown"
+	"github.com/rkgcloud/crud/pkg/storage"
+	"github.com/rkgcloud/crud/pkg/tasks"
+	"github.com/rkgcloud/crud/pkg/upgrade"
+	"github.com/rkgcloud/crud/pkg/validation"
+	"github.com/rkgcloud/crud/pkg/verification"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/cobra"
+)
+
+// newServeCommand creates the command running the HTTP service
+func newServeCommand(a *app) *cobra.Command {
+	return &cobra.Command{
+		Use:   "serve",
+		Short: "Migrate the database and run the HTTP service",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return serve(a)
+		},
+	}
+}
+
+// serve runs the service until it receives SIGINT or SIGTERM
+func serve(a *app) error {
+	cfg, flags, err := a.loadConfig()
+	if err != nil {
+		return err
+	}
+	if !cfg.DebugMode {
+		gin.SetMode(gin.ReleaseMode)
+	}
+	// Logs are also shipped to the configured log store, before the router
+	// picks up its request log writer
+	host, _ := os.Hostname()
+	logs := logship.New(cfg.LogSink, map[string]string{"service": "crud", "environment": cfg.Environment, "host": host})
+	if logs != nil {
+		redactLogs(cfg.LogRedact, logs)
+	}
+	// The upgrader is set up first so a new process takes over the listener
+	// well within the upgrade timeout
+	var upgrader *upgrade.Upgrader
+	if cfg.Upgrade.Enabled {
+		if upgrader, err = upgrade.New(cfg.Upgrade); err != nil {
+			return fmt.Errorf("failed to set up upgrades: %w", err)
+		}
+	}
+	db, err := a.openDB(cfg)
+	if err != nil {
+		return err
+	}
+	if err := migrate(db); err != nil {
+		return err
+	}
+
+	// Email verification and invitation tokens are signed with the first
+	// configured secret and checked against all of them
+	secrets := make([][]byte, 0, len(cfg.Secrets))
+	for _, secret := range cfg.Secrets {
+		secrets = append(secrets, []byte(secret))
+	}
+	verifier := verification.NewVerifier(secrets, "email", 24*time.Hour)
+	inviter := verification.NewVerifier(secrets, "invitation", 7*24*time.Hour)
+	downloads := verification.NewVerifier(secrets, "export", cfg.Exports.LinkTTL)
+	forms := verification.NewVerifier(secrets, "form", time.Hour)
+
+	// Files are kept in a local directory or an object store
+	store, err := storage.New(cfg.Storage, cfg.S3)
+	if err != nil {
+		return fmt.Errorf("failed to set up storage: %w", err)
+	}
+
+	// Exports are generated in the background and kept for the retention period
+	exporter, err := exports.NewExporter(db, store, cfg.Exports.QueueSize)
+	if err != nil {
+		return fmt.Errorf("failed to set up exports: %w", err)
+	}
+
+	// Emails are rendered from templates and sent in the background
+	var sender mail.Sender = mail.LogSender{}
+	if cfg.Mail.Mode == config.MailModeSMTP {
+		sender = mail.NewSMTPSender(cfg.Mail.SMTPHost, cfg.Mail.SMTPPort, cfg.Mail.SMTPUsername, cfg.Mail.SMTPPassword, cfg.Mail.From)
+	}
+	mailer := mail.NewMailer(sender, cfg.Mail.QueueSize, 2)
+
+	// Reload reload-safe settings on SIGHUP
+	watcher := config.NewWatcher(cfg, flags)
+	if l, ok := db.Logger.(*database.Logger); ok {
+		watcher.Subscribe(l)
+	}
+
+	// Operational events are posted to a chat webhook when one is configured
+	alerts := alert.New(cfg.Alerts, cfg.Environment)
+
+	// A lost database is detected and the connection pool recovered without a restart
+	monitor, err := database.NewMonitor(db, cfg.DatabaseMonitor, func(up bool, err error) {
+		if up {
+			alerts.Alert(alert.KindDatabaseRecovered, "database is reachable again")
+			return
+		}
+		alerts.Alert(alert.KindDatabaseLost, "database lost, connection pool reset: %v", err)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set up database monitor: %w", err)
+	}
+
+	// Relay domain events recorded in the outbox
+	publisher, err := newPublisher(cfg.Events)
+	if err != nil {
+		return fmt.Errorf("failed to set up event publisher: %w", err)
+	}
+	relay := outbox.NewRelay(db, publisher, cfg.Outbox.Interval, cfg.Outbox.BatchSize)
+	relay.OnFailure(func(event models.OutboxEvent, err error) {
+		alerts.Alert(alert.KindDelivery, "event %d (%s) failed after %d attempts: %v", event.ID, event.Type, event.Attempts, err)
+	})
+
+	// Rate limits are counted in process or shared through Redis
+	limits, closeLimits, err := newRateLimitStore(cfg.RateLimit)
+	if err != nil {
+		return fmt.Errorf("failed to set up rate limit store: %w", err)
+	}
+
+	// Recurring tasks; with several replicas, only the elected leader runs them
+	sched := scheduler.New()
+	var elector *scheduler.PostgresElector
+	if cfg.Schedules.LeaderElection {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return fmt.Errorf("failed to set up leader election: %w", err)
+		}
+		elector = scheduler.NewPostgresElector(sqlDB, 10*time.Second)
+		sched.SetElector(elector)
+	}
+	if cfg.Schedules.InvitationCleanup != "off" {
+		if err := sched.Register("invitation-cleanup", cfg.Schedules.InvitationCleanup, tasks.CleanupInvitations(db)); err != nil {
+			return err
+		}
+	}
+	if cfg.Schedules.ExportCleanup != "off" {
+		err := sched.Register("export-cleanup", cfg.Schedules.ExportCleanup, func(ctx context.Context) error {
+			return exporter.Cleanup(ctx, cfg.Exports.Retention)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if cfg.Schedules.UserPurge != "off" {
+		if err := sched.Register("user-purge", cfg.Schedules.UserPurge, tasks.PurgeDeletedUsers(db, cfg.DeletedUserRetention)); err != nil {
+			return err
+		}
+	}
+
+	// Request bodies are validated with the custom validators on binding
+	if err := validation.Register(cfg.Phone, cfg.UserMetadata); err != nil {
+		return fmt.Errorf("failed to register validators: %w", err)
+	}
+
+	// Background work starts once nothing can fail anymore, so a failed setup
+	// returns without leaving any of it running
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	go watcher.Watch(watchCtx)
+	monitorCtx, stopMonitor := context.WithCancel(context.Background())
+	go monitor.Run(monitorCtx)
+	relayCtx, stopRelay := context.WithCancel(context.Background())
+	go relay.Run(relayCtx)
+	electionCtx, stopElection := context.WithCancel(context.Background())
+	if elector != nil {
+		go elector.Run(electionCtx)
+	}
+	sched.Start()
+
+	// Work requests leave behind, such as email domain checks, is tracked so
+	// shutdown waits for it before closing the database
+	background := &shutdown.Group{}
+	r, _ := newRouter(services{
+		cfg:       cfg,
+		db:        db,
+		watcher:   watcher,
+		verifier:  verifier,
+		inviter:   inviter,
+		downloads: downloads,
+		forms:     forms,
+		mailer:    mailer,
+		exporter:  exporter,
+		sched:     sched,
+		limits:    limits,
+		alerts:    alerts,
+		// The router hands it to the handlers starting background work
+		background: background,
+	})
+
+	// Run server; if it stops serving, the service shuts down and reports why
+	srv := server.New(cfg, r, upgrader)
+	serveErr := make(chan error, 1)
+	serveFailed := make(chan struct{})
+	go func() {
+		if err := srv.ListenAndServe(); err != nil {
+			serveErr <- err
+			close(serveFailed)
+		}
+	}()
+
+	// Shut down in order: stop accepting and drain requests, then release everything they use
+	hooks := shutdown.NewRegistry()
+	hooks.TriggerOn(serveFailed, "Server stopped serving")
+	if upgrader != nil {
+		// Drain and exit once the new process has taken over
+		hooks.TriggerOn(upgrader.Exit(), "Upgraded")
+		upgradeCtx, stopUpgrades := context.WithCancel(context.Background())
+		go upgrader.Watch(upgradeCtx)
+		hooks.Register("upgrader", time.Second, func(ctx context.Context) error {
+			stopUpgrades()
+			return upgrader.Stop(ctx)
+		})
+	}
+	hooks.Register("http server", cfg.ShutdownTimeout, srv.Shutdown)
+	hooks.Register("background checks", 15*time.Second, background.Wait)
+	hooks.Register("scheduler", cfg.ShutdownTimeout, sched.Stop)
+	hooks.Register("leader election", 2*time.Second, func(ctx context.Context) error {
+		stopElection()
+		if elector == nil {
+			return nil
+		}
+		return elector.Wait(ctx)
+	})
+	hooks.Register("outbox relay", 10*time.Second, func(ctx context.Context) error {
+		stopRelay()
+		return relay.Wait(ctx)
+	})
+	hooks.Register("exports", cfg.ShutdownTimeout, exporter.Close)
+	hooks.Register("mail queue", 30*time.Second, mailer.Close)
+	hooks.Register("event publisher", 10*time.Second, func(ctx context.Context) error {
+		return publisher.Close()
+	})
+	hooks.Register("rate limit store", 5*time.Second, func(ctx context.Context) error {
+		return closeLimits()
+	})
+	hooks.Register("config watcher", time.Second, func(ctx context.Context) error {
+		stopWatching()
+		return nil
+	})
+	hooks.Register("database monitor", time.Second, func(ctx context.Context) error {
+		stopMonitor()
+		return monitor.Wait(ctx)
+	})
+	// Alerts are closed once the server, relay and monitor raising them have
+	// stopped; the ones raised by work that outlived its hook are dropped
+	hooks.Register("alerts", 10*time.Second, alerts.Close)
+	hooks.Register("database", 5*time.Second, func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.Close()
+	})
+	hooks.Register("log sink", 10*time.Second, logs.Close)
+	shutdownErr := hooks.GracefulShutdown()
+	select {
+	case err := <-serveErr:
+		return errors.Join(fmt.Errorf("server failed: %w", err), shutdownErr)
+	default:
+	}
+	if shutdownErr != nil {
+		return fmt.Errorf("shutdown incomplete: %w", shutdownErr)
+	}
+	log.Println("Shutdown complete")
+	return nil
+}
+
+// newPublisher creates the configured event publisher
+func newPublisher(cfg config.EventsConfig) (events.Publisher, error) {
+	switch cfg.Publisher {
+	case config.PublisherWebhook:
+		return events.NewWebhookPublisher(cfg.WebhookURL), nil
+	case config.PublisherKafka:
+		return events.NewKafkaPublisher(cfg.KafkaBrokers, cfg.KafkaTopic), nil
+	case config.PublisherNATS:
+		return events.NewNATSPublisher(cfg.NATSURL, cfg.NATSSubjectPrefix)
+	default:
+		return events.LogPublisher{}, nil
+	}
+}
+
+// newRateLimitStore creates the configured rate limit store and the function
+// releasing it
+func newRateLimitStore(cfg config.RateLimitConfig) (middleware.Store, func() error, error) {
+	if cfg.Store != config.RateLimitStoreRedis {
+		return middleware.NewMemoryStore(), func() error { return nil }, nil
+	}
+	opts, err := redis.ParseURL(cfg.RedisURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid RATE_LIMIT_REDIS_URL: %w", err)
+	}
+	client := redis.NewClient(opts)
+	return middleware.NewRedisStore(client), client.Close, nil
+}
@@ -0,0 +1,180 @@
+// Package alert posts operational alerts, such as panics or a service turning
+// unready, to a Slack or Microsoft Teams incoming webhook.
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rkgcloud/crud/pkg/config"
+	"github.com/rkgcloud/crud/pkg/httpclient"
+)
+
+// Kinds of alerts; each kind is rate limited on its own
+const (
+	KindPanic        = "panic"
+	KindServerErrors = "server errors"
+	KindDelivery     = "event delivery"
+	KindReadiness    = "readiness"
+	// The database alerts are separate kinds so a recovery is not held back
+	// by the loss reported just before
+	KindDatabaseLost      = "database lost"
+	KindDatabaseRecovered = "database recovered"
+)
+
+// queueSize is the number of alerts that may wait to be posted
+const queueSize = 16
+
+// message is an alert waiting to be posted
+type message struct {
+	kind string
+	text string
+}
+
+// Alerter posts alerts in the background, sending at most one alert of each
+// kind per interval and counting the ones it holds back. A nil *Alerter
+// discards every alert, so callers need not check whether alerts are enabled.
+type Alerter struct {
+	url      string
+	format   string
+	interval time.Duration
+	// source names the service and host in every alert
+	source string
+	client *httpclient.Client
+	queue  chan message
+	wg     sync.WaitGroup
+
+	mu         sync.Mutex
+	sent       map[string]time.Time
+	suppressed map[string]int
+	// closed is set by Close; later alerts are dropped
+	closed bool
+}
+
+// New creates an Alerter posting to the configured webhook, naming the
+// environment in every alert. It returns nil when no webhook is configured.
+func New(cfg config.AlertConfig, environment string) *Alerter {
+	if cfg.WebhookURL == "" {
+		return nil
+	}
+	host, _ := os.Hostname()
+	a := &Alerter{
+		url:        cfg.WebhookURL,
+		format:     cfg.Format,
+		interval:   cfg.Interval,
+		source:     fmt.Sprintf("crud %s on %s", environment, host),
+		client:     httpclient.New(10*time.Second, 2),
+		queue:      make(chan message, queueSize),
+		sent:       map[string]time.Time{},
+		suppressed: map[string]int{},
+	}
+	a.wg.Add(1)
+	go a.work()
+	return a
+}
+
+// Alert queues an alert of kind without blocking. It is dropped when an alert
+// of the same kind was sent within the interval, the queue is full or the
+// Alerter is closed.
+func (a *Alerter) Alert(kind, format string, args ...any) {
+	if a == nil {
+		return
+	}
+	text := fmt.Sprintf(format, args...)
+	now := time.Now()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.closed {
+		log.Printf("alerts are closed, dropping %s alert: %s\n", kind, text)
+		return
+	}
+	if now.Sub(a.sent[kind]) < a.interval {
+		a.suppressed[kind]++
+		return
+	}
+	if n := a.suppressed[kind]; n > 0 {
+		text += fmt.Sprintf("\n(%d similar alerts suppressed since %s)", n, a.sent[kind].UTC().Format(time.RFC3339))
+	}
+	a.sent[kind] = now
+	a.suppressed[kind] = 0
+
+	// The queue is sent to under mu, so Close cannot close it in between
+	select {
+	case a.queue <- message{kind: kind, text: text}:
+	default:
+		log.Printf("alert queue is full, dropping %s alert: %s\n", kind, text)
+	}
+}
+
+// Close stops accepting alerts and waits until the queued ones are posted or
+// ctx is done. Alerts raised afterwards are logged and dropped.
+func (a *Alerter) Close(ctx context.Context) error {
+	if a == nil {
+		return nil
+	}
+	a.mu.Lock()
+	if !a.closed {
+		a.closed = true
+		close(a.queue)
+	}
+	a.mu.Unlock()
+	done := make(chan struct{})
+	go func() {
+		a.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		a.client.CloseIdleConnections()
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%d alerts not sent: %w", len(a.queue), ctx.Err())
+	}
+}
+
+// work posts queued alerts until the queue is closed
+func (a *Alerter) work() {
+	defer a.wg.Done()
+	for msg := range a.queue {
+		if err := a.post(msg); err != nil {
+			log.Printf("failed to post %s alert: %v\n", msg.kind, err)
+		}
+	}
+}
+
+// post sends msg to the webhook in its format
+func (a *Alerter) post(msg message) error {
+	title := fmt.Sprintf("%s: %s", a.source, msg.kind)
+	var payload any
+	switch a.format {
+	case config.AlertFormatTeams:
+		payload = map[string]string{
+			"@type":    "MessageCard",
+			"@context": "https://schema.org/extensions",
+			"summary":  title,
+			"title":    title,
+			"text":     msg.text,
+		}
+	default:
+		payload = map[string]string{"text": fmt.Sprintf("*%s*\n%s", title, msg.text)}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	resp, err := a.client.Post(context.Background(), a.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+	return nil
+}
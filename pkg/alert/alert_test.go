@@ -0,0 +1,32 @@
+package alert
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rkgcloud/crud/pkg/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlertAfterCloseIsDropped(t *testing.T) {
+	posted := make(chan struct{}, 4)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posted <- struct{}{}
+	}))
+	defer srv.Close()
+	a := New(config.AlertConfig{WebhookURL: srv.URL, Format: config.AlertFormatSlack, Interval: time.Minute}, "test")
+
+	a.Alert(KindPanic, "before close")
+	require.NoError(t, a.Close(context.Background()))
+	assert.Len(t, posted, 1)
+
+	// Late producers must not panic on the closed queue, nor a second Close
+	assert.NotPanics(t, func() { a.Alert(KindDelivery, "after close") })
+	assert.NoError(t, a.Close(context.Background()))
+	assert.Len(t, posted, 1)
+}
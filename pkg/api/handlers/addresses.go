@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rkgcloud/crud/pkg/api/render"
+	"github.com/rkgcloud/crud/pkg/database"
+	"github.com/rkgcloud/crud/pkg/models"
+	"github.com/rkgcloud/crud/pkg/problem"
+	"github.com/rkgcloud/crud/pkg/validation"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// addressRequest is the body of an address creation or update
+type addressRequest struct {
+	Street     string `json:"street" binding:"required,max=200"`
+	City       string `json:"city" binding:"required,max=100"`
+	Region     string `json:"region" binding:"max=100"`
+	PostalCode string `json:"postal_code" binding:"max=20"`
+	Country    string `json:"country" binding:"required,country"`
+}
+
+// apply checks the postal code against the country and normalizes the request onto address
+func (r addressRequest) apply(address *models.Address) error {
+	if err := validation.PostalCode(r.Country, r.PostalCode); err != nil {
+		return err
+	}
+	country, err := validation.NormalizeCountry(r.Country)
+	if err != nil {
+		return err
+	}
+	address.Street, address.City, address.Region = r.Street, r.City, r.Region
+	address.PostalCode, address.Country = strings.TrimSpace(r.PostalCode), country
+	return nil
+}
+
+// addressResponse is an address as returned by the API
+type addressResponse struct {
+	ID         uint      `json:"id"`
+	UserID     uint      `json:"user_id"`
+	Street     string    `json:"street"`
+	City       string    `json:"city"`
+	Region     string    `json:"region,omitempty"`
+	PostalCode string    `json:"postal_code,omitempty"`
+	Country    string    `json:"country"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+func newAddressResponse(a models.Address) addressResponse {
+	return addressResponse{
+		ID:         a.ID,
+		UserID:     a.UserID,
+		Street:     a.Street,
+		City:       a.City,
+		Region:     a.Region,
+		PostalCode: a.PostalCode,
+		Country:    a.Country,
+		CreatedAt:  a.CreatedAt,
+		UpdatedAt:  a.UpdatedAt,
+	}
+}
+
+// AddressController serves the address routes nested under a user
+type AddressController struct{}
+
+// NewAddressController creates an AddressController
+func NewAddressController() *AddressController {
+	return &AddressController{}
+}
+
+// List retrieves all addresses of a user
+func (h *AddressController) List(c *gin.Context) error {
+	db := database.Get(c)
+	user, err := findAddressOwner(c, db)
+	if err != nil {
+		return err
+	}
+	page, err := render.ParsePage(c)
+	if err != nil {
+		return problem.Validation(err)
+	}
+	var addresses []models.Address
+	total, err := paginate(db.Where("user_id = ?", user.ID), page, &addresses)
+	if err != nil {
+		return dbError(err, "addresses", "retrieve")
+	}
+	resp := make([]addressResponse, 0, len(addresses))
+	for _, address := range addresses {
+		resp = append(resp, newAddressResponse(address))
+	}
+	return render.List(c, resp, page, total)
+}
+
+// Create adds an address to a user
+func (h *AddressController) Create(c *gin.Context) error {
+	db := database.Get(c)
+	user, err := findAddressOwner(c, db)
+	if err != nil {
+		return err
+	}
+	var req addressRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		return problem.Validation(err)
+	}
+	address := models.Address{UserID: user.ID}
+	if err := req.apply(&address); err != nil {
+		return problem.Validation(err)
+	}
+	if err := db.Create(&address).Error; err != nil {
+		return dbError(err, "address", "create")
+	}
+	return render.One(c, http.StatusCreated, newAddressResponse(address))
+}
+
+// Get retrieves a single address of a user
+func (h *AddressController) Get(c *gin.Context) error {
+	db := database.Get(c)
+	address, err := findAddress(c, db)
+	if err != nil {
+		return err
+	}
+	return render.One(c, http.StatusOK, newAddressResponse(address))
+}
+
+// Update updates an address of a user
+func (h *AddressController) Update(c *gin.Context) error {
+	db := database.Get(c)
+	address, err := findAddress(c, db)
+	if err != nil {
+		return err
+	}
+	var req addressRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		return problem.Validation(err)
+	}
+	if err := req.apply(&address); err != nil {
+		return problem.Validation(err)
+	}
+	if err := db.Save(&address).Error; err != nil {
+		return dbError(err, "address", "update")
+	}
+	return render.One(c, http.StatusOK, newAddressResponse(address))
+}
+
+// Delete deletes an address of a user
+func (h *AddressController) Delete(c *gin.Context) error {
+	db := database.Get(c)
+	address, err := findAddress(c, db)
+	if err != nil {
+		return err
+	}
+	if err := db.Delete(&address).Error; err != nil {
+		return dbError(err, "address", "delete")
+	}
+	return render.One(c, http.StatusOK, gin.H{"message": "Address deleted"})
+}
+
+// findAddressOwner loads the user from the id path parameter, failing with a
+// 404 when missing
+func findAddressOwner(c *gin.Context, db *gorm.DB) (models.User, error) {
+	var user models.User
+	id, err := paramID(c, "id", "user")
+	if err != nil {
+		return user, err
+	}
+	if err := db.First(&user, id).Error; err != nil {
+		return user, dbError(err, "user", "retrieve")
+	}
+	return user, nil
+}
+
+// findAddress loads the address_id address of the id user, failing with a
+// 404 when missing
+func findAddress(c *gin.Context, db *gorm.DB) (models.Address, error) {
+	var address models.Address
+	userID, err := paramID(c, "id", "user")
+	if err != nil {
+		return address, err
+	}
+	id, err := paramID(c, "address_id", "address")
+	if err != nil {
+		return address, err
+	}
+	if err := db.Where("user_id = ?", userID).First(&address, id).Error; err != nil {
+		return address, dbError(err, "address", "retrieve")
+	}
+	return address, nil
+}
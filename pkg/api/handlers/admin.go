@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/rkgcloud/crud/pkg/api/render"
+	"github.com/rkgcloud/crud/pkg/config"
+	"github.com/rkgcloud/crud/pkg/debug"
+	"github.com/rkgcloud/crud/pkg/scheduler"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminController serves the admin routes inspecting the running service
+type AdminController struct {
+	watcher   *config.Watcher
+	scheduler *scheduler.Scheduler
+	routes    *debug.RouteTable
+}
+
+// NewAdminController creates an AdminController reporting on the
+// configuration of watcher, the tasks of s and the routes of routes
+func NewAdminController(watcher *config.Watcher, s *scheduler.Scheduler, routes *debug.RouteTable) *AdminController {
+	return &AdminController{watcher: watcher, scheduler: s, routes: routes}
+}
+
+// Config returns the effective configuration with secrets masked
+func (h *AdminController) Config(c *gin.Context) error {
+	return render.One(c, http.StatusOK, h.watcher.Current().Redacted())
+}
+
+// ScheduledTasks reports the last and next run of every scheduled task
+func (h *AdminController) ScheduledTasks(c *gin.Context) error {
+	return render.One(c, http.StatusOK, h.scheduler.Statuses())
+}
+
+// Routes lists every route with its middleware, handler and timeout
+func (h *AdminController) Routes(c *gin.Context) error {
+	return render.One(c, http.StatusOK, h.routes.Routes())
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/rkgcloud/crud/pkg/api/render"
+	"github.com/rkgcloud/crud/pkg/audit"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// AuditController serves the audit log routes
+type AuditController struct {
+	db *gorm.DB
+}
+
+// NewAuditController creates an AuditController
+func NewAuditController(db *gorm.DB) *AuditController {
+	return &AuditController{db: db}
+}
+
+// Verify checks the audit log hash chain and reports the entries that were
+// modified, deleted or inserted out of the chain
+func (h *AuditController) Verify(c *gin.Context) error {
+	report, err := audit.Verify(c.Request.Context(), h.db)
+	if err != nil {
+		return dbError(err, "audit log", "verify")
+	}
+	return render.One(c, http.StatusOK, report)
+}
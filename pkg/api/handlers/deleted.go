@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/rkgcloud/crud/pkg/api/render"
+	"github.com/rkgcloud/crud/pkg/database"
+	"github.com/rkgcloud/crud/pkg/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+// deletedUserResponse is a soft-deleted user awaiting its purge
+type deletedUserResponse struct {
+	userResponse
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// ListDeleted retrieves the soft-deleted users, which are kept until
+// DELETED_USER_RETENTION has passed
+func (h *UserController) ListDeleted(c *gin.Context) error {
+	page, err := render.ParsePage(c)
+	if err != nil {
+		return problem.Validation(err)
+	}
+	users, total, err := h.users.ListDeleted(database.Context(c), page.Offset(), page.Limit)
+	if err != nil {
+		return dbError(err, "users", "retrieve")
+	}
+	resp := make([]deletedUserResponse, 0, len(users))
+	for _, user := range users {
+		resp = append(resp, deletedUserResponse{
+			userResponse: newUserResponse(user),
+			DeletedAt:    user.DeletedAt.Time.In(user.Location()),
+		})
+	}
+	return render.List(c, resp, page, total)
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/rkgcloud/crud/pkg/mail"
+	"github.com/rkgcloud/crud/pkg/models"
+	"github.com/rkgcloud/crud/pkg/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// mxCheckTimeout bounds the DNS lookups of a single MX check
+const mxCheckTimeout = 10 * time.Second
+
+// checkEmailDomain looks up the mail servers of the user's email domain in the
+// background and records an audit entry when the domain cannot receive email.
+// Shutdown waits for the check before closing the database.
+func (h *UserController) checkEmailDomain(c *gin.Context, user models.User) {
+	// The check outlives the request, so it must not be cancelled with it
+	ctx := context.WithoutCancel(c.Request.Context())
+	h.background.Go(func() {
+		lookupCtx, cancel := context.WithTimeout(ctx, mxCheckTimeout)
+		defer cancel()
+		domain := validation.EmailDomain(user.Email)
+		err := mail.CheckMX(lookupCtx, domain)
+		switch {
+		case err == nil:
+			return
+		case errors.Is(err, mail.ErrNoMailServer):
+			log.Printf("user %d: email domain %s does not accept email\n", user.ID, domain)
+			if err := h.users.RecordUndeliverable(ctx, user, domain); err != nil {
+				log.Printf("Error recording undeliverable email of user %d: %v\n", user.ID, err)
+			}
+		default:
+			log.Printf("user %d: could not check email domain: %v\n", user.ID, err)
+		}
+	})
+}
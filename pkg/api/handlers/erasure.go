@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/rkgcloud/crud/pkg/api/render"
+	"github.com/rkgcloud/crud/pkg/database"
+	"github.com/rkgcloud/crud/pkg/exports"
+	"github.com/rkgcloud/crud/pkg/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// erasureResponse reports what an erasure changed
+type erasureResponse struct {
+	repository.ErasureReport
+	ExportsDeleted int `json:"exports_deleted"`
+}
+
+// ErasureController serves the right-to-be-forgotten route
+type ErasureController struct {
+	users    repository.Users
+	exporter *exports.Exporter
+}
+
+// NewErasureController creates an ErasureController
+func NewErasureController(users repository.Users, exporter *exports.Exporter) *ErasureController {
+	return &ErasureController{users: users, exporter: exporter}
+}
+
+// Erase anonymizes the name, email and phone of a user in the users table,
+// outbox events and audit log, deletes their addresses and invitations, and
+// deletes finished exports, which may contain them. The user row is kept so
+// records referring to it stay valid.
+func (h *ErasureController) Erase(c *gin.Context) error {
+	user, err := findUser(c, h.users)
+	if err != nil {
+		return err
+	}
+	report, err := h.users.Erase(database.Context(c), &user)
+	if err != nil {
+		return dbError(err, "user", "erase")
+	}
+	resp := erasureResponse{ErasureReport: report}
+	// The user is already anonymized; exports left behind expire with their retention
+	if resp.ExportsDeleted, err = h.exporter.DeleteFinished(c.Request.Context()); err != nil {
+		log.Printf("erasure of user %d: deleting exports: %v\n", user.ID, err)
+	}
+	return render.One(c, http.StatusOK, resp)
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/rkgcloud/crud/pkg/dberr"
+	"github.com/rkgcloud/crud/pkg/problem"
+)
+
+// dbError is the problem matching a database error: 404 for a missing
+// resource, 409 for a duplicate, 422 for other constraint violations, 503
+// when the request ran out of DB_REQUEST_TIMEOUT and 500 for everything
+// else, described as failing to action it
+func dbError(err error, resource, action string) error {
+	err = dberr.Translate(err)
+	title := strings.ToUpper(resource[:1]) + resource[1:]
+	switch {
+	case errors.Is(err, dberr.ErrNotFound):
+		return problem.New(http.StatusNotFound, title+" not found")
+	case errors.Is(err, dberr.ErrConflict):
+		return problem.New(http.StatusConflict, title+" already exists")
+	case errors.Is(err, dberr.ErrConstraint):
+		return problem.New(http.StatusUnprocessableEntity, title+" violates a database constraint")
+	case errors.Is(err, context.DeadlineExceeded):
+		log.Printf("Could not %s %s in time: %v\n", action, resource, err)
+		return problem.New(http.StatusServiceUnavailable, "Could not "+action+" "+resource+" in time")
+	default:
+		log.Printf("Could not %s %s: %v\n", action, resource, err)
+		return problem.New(http.StatusInternalServerError, "Could not "+action+" "+resource)
+	}
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/rkgcloud/crud/pkg/api/render"
+	"github.com/rkgcloud/crud/pkg/database"
+	"github.com/rkgcloud/crud/pkg/exports"
+	"github.com/rkgcloud/crud/pkg/models"
+	"github.com/rkgcloud/crud/pkg/problem"
+	"github.com/rkgcloud/crud/pkg/storage"
+	"github.com/rkgcloud/crud/pkg/verification"
+
+	"github.com/gin-gonic/gin"
+)
+
+// createExportRequest is the body of an export request
+type createExportRequest struct {
+	Format string `json:"format"`
+}
+
+// exportResponse is an export job with its download link once completed
+type exportResponse struct {
+	ID          uint       `json:"id"`
+	Format      string     `json:"format"`
+	Status      string     `json:"status"`
+	Rows        int        `json:"rows"`
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	DownloadURL string     `json:"download_url,omitempty"`
+}
+
+func newExportResponse(job models.ExportJob) exportResponse {
+	return exportResponse{
+		ID:          job.ID,
+		Format:      job.Format,
+		Status:      job.Status,
+		Rows:        job.Rows,
+		Error:       job.Error,
+		CreatedAt:   job.CreatedAt,
+		CompletedAt: job.CompletedAt,
+	}
+}
+
+// signedURLTTL is how long the storage link a download is redirected to stays
+// valid; the download link itself is checked beforehand
+const signedURLTTL = 5 * time.Minute
+
+// ExportController serves the export routes
+type ExportController struct {
+	exporter *exports.Exporter
+	verifier *verification.Verifier
+	baseURL  string
+}
+
+// NewExportController creates an ExportController whose download links are
+// built on baseURL and signed by verifier
+func NewExportController(exporter *exports.Exporter, verifier *verification.Verifier, baseURL string) *ExportController {
+	return &ExportController{exporter: exporter, verifier: verifier, baseURL: baseURL}
+}
+
+// Create queues a background export of all users
+func (h *ExportController) Create(c *gin.Context) error {
+	req := createExportRequest{Format: exports.FormatCSV}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			return problem.Validation(err)
+		}
+	}
+	if req.Format != exports.FormatCSV {
+		return problem.New(http.StatusBadRequest, "Unsupported export format")
+	}
+	job, err := h.exporter.Enqueue(req.Format)
+	if err != nil {
+		if errors.Is(err, exports.ErrQueueFull) {
+			return problem.New(http.StatusServiceUnavailable, "Too many exports in progress")
+		}
+		return dbError(err, "export", "create")
+	}
+	c.Header("Location", "/exports/"+strconv.FormatUint(uint64(job.ID), 10))
+	return render.One(c, http.StatusAccepted, newExportResponse(*job))
+}
+
+// Get reports the status of an export and a signed download link once it completed
+func (h *ExportController) Get(c *gin.Context) error {
+	db := database.Get(c)
+	var job models.ExportJob
+	id, err := paramID(c, "id", "export")
+	if err != nil {
+		return err
+	}
+	if err := db.First(&job, id).Error; err != nil {
+		return dbError(err, "export", "retrieve")
+	}
+	resp := newExportResponse(job)
+	if job.Status == models.ExportCompleted {
+		resp.DownloadURL = publicURL(h.baseURL, "/exports/"+strconv.FormatUint(uint64(job.ID), 10)+"/download", url.Values{"token": {h.verifier.Token(job.ID, job.FileName)}})
+	}
+	return render.One(c, http.StatusOK, resp)
+}
+
+// Download serves a completed export file to holders of a valid download token
+func (h *ExportController) Download(c *gin.Context) error {
+	db := database.Get(c)
+	id, fileName, err := h.verifier.Verify(c.Query("token"))
+	if err != nil || strconv.FormatUint(uint64(id), 10) != c.Param("id") {
+		return problem.New(http.StatusForbidden, "Invalid or expired download link")
+	}
+	var job models.ExportJob
+	if err := db.First(&job, id).Error; err != nil || job.FileName != fileName || job.Status != models.ExportCompleted {
+		return problem.New(http.StatusNotFound, "Export not found")
+	}
+	// Files in object storage are downloaded from it directly
+	link, err := h.exporter.SignedURL(c.Request.Context(), job, signedURLTTL)
+	if err != nil {
+		return dbError(err, "export", "retrieve")
+	}
+	if link != "" {
+		return render.Redirect(c, http.StatusFound, link)
+	}
+	f, err := h.exporter.Open(c.Request.Context(), job)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotExist) {
+			return problem.New(http.StatusNotFound, "Export not found")
+		}
+		return dbError(err, "export", "retrieve")
+	}
+	defer f.Close()
+	return render.Reader(c, "text/csv", f, map[string]string{
+		"Content-Disposition": `attachment; filename="users.` + job.Format + `"`,
+	})
+}
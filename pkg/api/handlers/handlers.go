@@ -1,81 +1,288 @@
 package handlers
 
 import (
+	"encoding/json"
+	"maps"
 	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/rkgcloud/crud/pkg/api/render"
+	"github.com/rkgcloud/crud/pkg/config"
+	"github.com/rkgcloud/crud/pkg/database"
+	"github.com/rkgcloud/crud/pkg/filter"
 	"github.com/rkgcloud/crud/pkg/models"
+	"github.com/rkgcloud/crud/pkg/problem"
+	"github.com/rkgcloud/crud/pkg/repository"
+	"github.com/rkgcloud/crud/pkg/shutdown"
+	"github.com/rkgcloud/crud/pkg/validation"
+	"github.com/rkgcloud/crud/pkg/verification"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
-// CreateUser creates a new user in the database
-func CreateUser(c *gin.Context, db *gorm.DB) {
+// userRequest is the body of a user creation or update. Only these fields
+// can be set by clients; IDs, timestamps, verification and role are managed
+// by the service.
+type userRequest struct {
+	Name  string `json:"name" binding:"required"`
+	Email string `json:"email" binding:"required,email_address"`
+	Age   int    `json:"age" binding:"required"`
+	Phone string `json:"phone" binding:"omitempty,phone"`
+	// Timezone and Locale are the preferences used to show timestamps
+	Timezone string `json:"timezone" binding:"omitempty,timezone"`
+	Locale   string `json:"locale" binding:"omitempty,bcp47_language_tag"`
+	// Metadata replaces the metadata of the user when present
+	Metadata map[string]any `json:"metadata"`
+}
+
+// apply normalizes the request onto user
+func (r userRequest) apply(user *models.User) error {
+	email, err := validation.NormalizeEmail(r.Email)
+	if err != nil {
+		return err
+	}
+	user.Name, user.Email, user.Age = r.Name, email, r.Age
+	user.Timezone, user.Locale = r.Timezone, r.Locale
+	user.Phone, user.PhoneDisplay = "", ""
+	if r.Phone != "" {
+		if user.Phone, user.PhoneDisplay, err = validation.NormalizePhone(r.Phone); err != nil {
+			return err
+		}
+	}
+	if r.Metadata != nil {
+		if err := validation.CheckMetadata(r.Metadata); err != nil {
+			return err
+		}
+		user.Metadata, err = json.Marshal(r.Metadata)
+	} else if user.Metadata == nil {
+		user.Metadata = json.RawMessage("{}")
+	}
+	return err
+}
+
+// userResponse is a user as returned by the API
+type userResponse struct {
+	ID           uint            `json:"id"`
+	Name         string          `json:"name"`
+	Email        string          `json:"email"`
+	Age          int             `json:"age"`
+	Phone        string          `json:"phone,omitempty"`
+	PhoneDisplay string          `json:"phone_display,omitempty"`
+	Verified     bool            `json:"verified"`
+	Role         string          `json:"role"`
+	Timezone     string          `json:"timezone,omitempty"`
+	Locale       string          `json:"locale,omitempty"`
+	Metadata     json.RawMessage `json:"metadata,omitempty"`
+	CreatedAt    time.Time       `json:"created_at"`
+	UpdatedAt    time.Time       `json:"updated_at"`
+}
 
-	//body, err := io.ReadAll(c.Request.Body)
-	//if err != nil {
-	//	log.Printf("Error parsing request body: %v\n", err)
-	//}
-	//log.Printf("Request body: %v\n", string(body))
+// newUserResponse shows the timestamps of user in their time zone
+func newUserResponse(user models.User) userResponse {
+	return userResponse{
+		ID:           user.ID,
+		Name:         user.Name,
+		Email:        user.Email,
+		Age:          user.Age,
+		Phone:        user.Phone,
+		PhoneDisplay: user.PhoneDisplay,
+		Verified:     user.Verified,
+		Role:         user.Role,
+		Timezone:     user.Timezone,
+		Locale:       user.Locale,
+		Metadata:     user.Metadata,
+		CreatedAt:    user.CreatedAt.In(user.Location()),
+		UpdatedAt:    user.UpdatedAt.In(user.Location()),
+	}
+}
 
-	var user models.User
-	if err := c.ShouldBindJSON(&user); err != nil {
+// userFilterFields are the fields users can be filtered on with ?filter=
+var userFilterFields = filter.Fields{
+	"name":       {Column: "name", Kind: filter.String},
+	"email":      {Column: "email", Kind: filter.String, Lowercase: true},
+	"phone":      {Column: "phone", Kind: filter.String},
+	"age":        {Column: "age", Kind: filter.Int},
+	"role":       {Column: "role", Kind: filter.String},
+	"verified":   {Column: "verified", Kind: filter.Bool},
+	"created_at": {Column: "created_at", Kind: filter.Time},
+}
+
+// Mailer sends the emails of the user and invitation flows. *mail.Mailer
+// implements it; tests can use the generated mocks.Mailer.
+type Mailer interface {
+	SendVerification(to, name, link string, expiresAt time.Time) error
+	SendInvitation(to, role, link string, expiresAt time.Time) error
+}
+
+// UserController serves the user routes
+type UserController struct {
+	users    repository.Users
+	verifier *verification.Verifier
+	mailer   Mailer
+	// checkMX looks up the mail servers of new email domains in the background
+	checkMX bool
+	// background runs the checks outliving their request
+	background *shutdown.Group
+	// baseURL is the PUBLIC_URL verification links point to
+	baseURL string
+}
+
+// NewUserController creates a UserController sending verification links on
+// baseURL signed by verifier through mailer. Background checks run in
+// background, so shutdown can wait for them.
+func NewUserController(users repository.Users, verifier *verification.Verifier, mailer Mailer, cfg config.MailConfig, background *shutdown.Group, baseURL string) *UserController {
+	return &UserController{users: users, verifier: verifier, mailer: mailer, checkMX: cfg.CheckMX, background: background, baseURL: baseURL}
+}
+
+// Create creates a new user in the database and sends a verification link
+func (h *UserController) Create(c *gin.Context) error {
+	var req userRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		return problem.Validation(err)
+	}
+	user := models.User{Role: models.RoleUser}
+	if err := req.apply(&user); err != nil {
+		return problem.Validation(err)
+	}
+	if err := h.users.Create(database.Context(c), &user); err != nil {
+		return dbError(err, "user", "create")
+	}
+	if h.checkMX {
+		h.checkEmailDomain(c, user)
+	}
+	// The user exists either way; a failed send can be retried through ResendVerification
+	_ = h.sendVerification(user)
+	return render.One(c, http.StatusOK, newUserResponse(user))
+}
 
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+// List retrieves the users matching the optional filter expression, tag and
+// metadata values
+func (h *UserController) List(c *gin.Context) error {
+	page, err := render.ParsePage(c)
+	if err != nil {
+		return problem.Validation(err)
+	}
+	conds, err := filter.Parse(c.Query("filter"), userFilterFields)
+	if err != nil {
+		return problem.Validation(err)
 	}
-	if err := db.Create(&user).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create user"})
-		return
+	if c.Query("tag") != "" {
+		tag, err := parseTag("tag", c.Query("tag"))
+		if err != nil {
+			return problem.Validation(err)
+		}
+		conds = append(conds, repository.Tagged(models.TaggableUser, tag))
 	}
-	c.JSON(http.StatusOK, user)
+	metadata, err := metadataConditions(c.Request.URL.Query())
+	if err != nil {
+		return problem.Validation(err)
+	}
+	conds = append(conds, metadata...)
+	users, total, err := h.users.List(database.Context(c), conds, page.Offset(), page.Limit)
+	if err != nil {
+		return dbError(err, "users", "retrieve")
+	}
+	resp := make([]userResponse, 0, len(users))
+	for _, user := range users {
+		resp = append(resp, newUserResponse(user))
+	}
+	return render.List(c, resp, page, total)
 }
 
-// GetUsers retrieves all users from the database
-func GetUsers(c *gin.Context, db *gorm.DB) {
-	var users []models.User
-	if err := db.Find(&users).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not retrieve users"})
-		return
+// Get retrieves a single user by ID
+func (h *UserController) Get(c *gin.Context) error {
+	user, err := findUser(c, h.users)
+	if err != nil {
+		return err
 	}
-	c.JSON(http.StatusOK, users)
+	return render.One(c, http.StatusOK, newUserResponse(user))
 }
 
-// GetUser retrieves a single user by ID
-func GetUser(c *gin.Context, db *gorm.DB) {
-	var user models.User
-	id := c.Param("id")
-	if err := db.First(&user, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
-		return
+// Update updates a user's information
+func (h *UserController) Update(c *gin.Context) error {
+	user, err := findUser(c, h.users)
+	if err != nil {
+		return err
+	}
+	var req userRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		return problem.Validation(err)
+	}
+	email := user.Email
+	if err := req.apply(&user); err != nil {
+		return problem.Validation(err)
 	}
-	c.JSON(http.StatusOK, user)
+	// Verification can only be granted through Verify and is reset when the address changes
+	user.Verified = user.Verified && strings.EqualFold(user.Email, email)
+	if err := h.users.Update(database.Context(c), &user); err != nil {
+		return dbError(err, "user", "update")
+	}
+	if h.checkMX && !strings.EqualFold(user.Email, email) {
+		h.checkEmailDomain(c, user)
+	}
+	return render.One(c, http.StatusOK, newUserResponse(user))
 }
 
-// UpdateUser updates a user's information
-func UpdateUser(c *gin.Context, db *gorm.DB) {
-	var user models.User
-	id := c.Param("id")
-	if err := db.First(&user, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
-		return
+// Delete deletes a user from the database
+func (h *UserController) Delete(c *gin.Context) error {
+	user, err := findUser(c, h.users)
+	if err != nil {
+		return err
+	}
+	if err := h.users.Delete(database.Context(c), &user); err != nil {
+		return dbError(err, "user", "delete")
 	}
-	if err := c.ShouldBindJSON(&user); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+	return render.One(c, http.StatusOK, gin.H{"message": "User deleted"})
+}
+
+// findUser loads the user from the id path parameter, failing with a 404
+// when missing
+func findUser(c *gin.Context, users repository.Users) (models.User, error) {
+	id, err := paramID(c, "id", "user")
+	if err != nil {
+		return models.User{}, err
+	}
+	user, err := users.Get(database.Context(c), id)
+	if err != nil {
+		return user, dbError(err, "user", "retrieve")
+	}
+	return user, nil
+}
+
+// metadataConditions matches the metadata given as metadata.<key>=<value>
+// query parameters, with a containment test the GIN index on metadata serves
+func metadataConditions(query url.Values) ([]filter.Condition, error) {
+	var conds []filter.Condition
+	for _, param := range slices.Sorted(maps.Keys(query)) {
+		key, ok := strings.CutPrefix(param, "metadata.")
+		if !ok {
+			continue
+		}
+		value, err := validation.ParseMetadata(key, query.Get(param))
+		if err != nil {
+			return nil, err
+		}
+		contained, err := json.Marshal(map[string]any{key: value})
+		if err != nil {
+			return nil, err
+		}
+		conds = append(conds, filter.Condition{Column: "metadata", Op: "@>", Value: string(contained)})
 	}
-	db.Save(&user)
-	c.JSON(http.StatusOK, user)
+	return conds, nil
 }
 
-// DeleteUser deletes a user from the database
-func DeleteUser(c *gin.Context, db *gorm.DB) {
-	var user models.User
-	id := c.Param("id")
-	if err := db.First(&user, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
-		return
+// paramID parses the name path parameter as the ID of resource, failing with
+// a 404 when it is not one. IDs must not reach gorm as strings, which it
+// treats as SQL conditions unless they are numeric.
+func paramID(c *gin.Context, name, resource string) (uint, error) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 0)
+	if err != nil || id == 0 {
+		return 0, dbError(gorm.ErrRecordNotFound, resource, "retrieve")
 	}
-	db.Delete(&user)
-	c.JSON(http.StatusOK, gin.H{"message": "User deleted"})
+	return uint(id), nil
 }
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rkgcloud/crud/pkg/api/render"
+	"github.com/rkgcloud/crud/pkg/config"
+	"github.com/rkgcloud/crud/pkg/database"
+	"github.com/rkgcloud/crud/pkg/models"
+	"github.com/rkgcloud/crud/pkg/problem"
+	"github.com/rkgcloud/crud/pkg/shutdown"
+	"github.com/rkgcloud/crud/pkg/testing/mocks"
+	"github.com/rkgcloud/crud/pkg/validation"
+	"github.com/rkgcloud/crud/pkg/verification"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	if err := validation.Register(config.PhoneConfig{DefaultRegion: "US"}, nil); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// testPublicURL is the PUBLIC_URL links of the test controllers point to
+const testPublicURL = "https://crud.example.com"
+
+// testVerifier signs the verification links of the test controllers
+var testVerifier = verification.NewVerifier([][]byte{[]byte("test-secret-test-secret-test-sec")}, "email", time.Hour)
+
+// newUserEngine serves the user routes of a UserController backed by users
+// and mailer. The database session only carries the request context, the
+// repository is the mock.
+func newUserEngine(t *testing.T, users *mocks.Users, mailer *mocks.Mailer) *gin.Engine {
+	t.Helper()
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	require.NoError(t, err)
+	ctl := NewUserController(users, testVerifier, mailer, config.MailConfig{}, &shutdown.Group{}, testPublicURL)
+	r := gin.New()
+	r.Use(problem.Handler(), database.Session(db, time.Second))
+	r.POST("/users", render.Handle(ctl.Create))
+	r.GET("/users/verify", render.Handle(ctl.Verify))
+	r.GET("/users/:id", render.Handle(ctl.Get))
+	r.PUT("/users/:id", render.Handle(ctl.Update))
+	r.POST("/users/:id/verification", render.Handle(ctl.ResendVerification))
+	return r
+}
+
+// send serves a request with an optional JSON body
+func send(r *gin.Engine, method, target, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// decodeUser reads the user of a {data} response
+func decodeUser(t *testing.T, w *httptest.ResponseRecorder) userResponse {
+	t.Helper()
+	var resp struct {
+		Data userResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return resp.Data
+}
+
+func TestUserControllerCreate(t *testing.T) {
+	users := mocks.NewUsers(t)
+	mailer := mocks.NewMailer(t)
+	users.EXPECT().Create(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, user *models.User) error {
+		user.ID = 7
+		return nil
+	})
+	mailer.EXPECT().SendVerification("ann@example.com", "Ann", mock.MatchedBy(func(link string) bool {
+		return strings.HasPrefix(link, testPublicURL+"/users/verify?token=")
+	}), mock.Anything).Return(nil)
+
+	// The Host header is the client's and must not end up in the link
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{"name":"Ann","email":" Ann@Example.com ","age":30}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Host = "attacker.example"
+	w := httptest.NewRecorder()
+	newUserEngine(t, users, mailer).ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	user := decodeUser(t, w)
+	assert.Equal(t, uint(7), user.ID)
+	assert.Equal(t, "ann@example.com", user.Email)
+	assert.Equal(t, models.RoleUser, user.Role)
+	assert.False(t, user.Verified)
+}
+
+func TestUserControllerCreateRejectsInvalidBody(t *testing.T) {
+	// Neither mock expects a call, so reaching the repository or mailer fails the test
+	r := newUserEngine(t, mocks.NewUsers(t), mocks.NewMailer(t))
+
+	w := send(r, http.MethodPost, "/users", `{"name":"Ann","email":"not an address","age":30}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), `"field":"email"`)
+}
+
+func TestUserControllerCreateConflict(t *testing.T) {
+	users := mocks.NewUsers(t)
+	users.EXPECT().Create(mock.Anything, mock.Anything).Return(&pgconn.PgError{Code: "23505", ConstraintName: "idx_users_email"})
+
+	w := send(newUserEngine(t, users, mocks.NewMailer(t)), http.MethodPost, "/users", `{"name":"Ann","email":"ann@example.com","age":30}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "User already exists")
+}
+
+func TestUserControllerGet(t *testing.T) {
+	users := mocks.NewUsers(t)
+	users.EXPECT().Get(mock.Anything, uint(7)).Return(models.User{Model: gorm.Model{ID: 7}, Name: "Ann", Email: "ann@example.com", Role: models.RoleUser}, nil)
+	users.EXPECT().Get(mock.Anything, uint(8)).Return(models.User{}, gorm.ErrRecordNotFound)
+	r := newUserEngine(t, users, mocks.NewMailer(t))
+
+	w := send(r, http.MethodGet, "/users/7", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "Ann", decodeUser(t, w).Name)
+
+	w = send(r, http.MethodGet, "/users/8", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "User not found")
+
+	// IDs that are not numbers never reach the repository
+	w = send(r, http.MethodGet, "/users/1%20OR%201=1", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestUserControllerUpdate(t *testing.T) {
+	users := mocks.NewUsers(t)
+	users.EXPECT().Get(mock.Anything, uint(7)).Return(models.User{Model: gorm.Model{ID: 7}, Name: "Ann", Email: "ann@example.com", Verified: true, Role: models.RoleAdmin}, nil)
+	var saved models.User
+	users.EXPECT().Update(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, user *models.User) error {
+		saved = *user
+		return nil
+	})
+
+	w := send(newUserEngine(t, users, mocks.NewMailer(t)), http.MethodPut, "/users/7", `{"name":"Ann B","email":"ann.b@example.com","age":31}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "Ann B", saved.Name)
+	assert.Equal(t, "ann.b@example.com", saved.Email)
+	// A changed address must be verified again, and the role is not the client's to set
+	assert.False(t, saved.Verified)
+	assert.Equal(t, models.RoleAdmin, saved.Role)
+	assert.Equal(t, "ann.b@example.com", decodeUser(t, w).Email)
+}
+
+func TestUserControllerUpdateKeepsVerificationOfSameAddress(t *testing.T) {
+	users := mocks.NewUsers(t)
+	users.EXPECT().Get(mock.Anything, uint(7)).Return(models.User{Model: gorm.Model{ID: 7}, Name: "Ann", Email: "ann@example.com", Verified: true}, nil)
+	users.EXPECT().Update(mock.Anything, mock.Anything).Return(nil)
+
+	w := send(newUserEngine(t, users, mocks.NewMailer(t)), http.MethodPut, "/users/7", `{"name":"Ann","email":"ANN@example.com","age":31}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.True(t, decodeUser(t, w).Verified)
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/rkgcloud/crud/pkg/api/render"
+	"github.com/rkgcloud/crud/pkg/dberr"
+	"github.com/rkgcloud/crud/pkg/models"
+	"github.com/rkgcloud/crud/pkg/repository"
+	"github.com/rkgcloud/crud/pkg/validation"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// maxImportLine bounds the size of a single imported record
+const maxImportLine = 1 << 20
+
+// importResult reports the outcome of one imported line
+type importResult struct {
+	Line   int                     `json:"line"`
+	Status string                  `json:"status"`
+	ID     uint                    `json:"id,omitempty"`
+	Error  string                  `json:"error,omitempty"`
+	Fields []validation.FieldError `json:"fields,omitempty"`
+}
+
+// importSummary is the last line of an import response
+type importSummary struct {
+	Status  string `json:"status"`
+	Created int    `json:"created"`
+	Failed  int    `json:"failed"`
+}
+
+// Import creates a user from every line of a newline-delimited JSON body,
+// streaming one result per line back as NDJSON. Lines are read and committed
+// one at a time, so memory use does not grow with the size of the import.
+func (h *UserController) Import(c *gin.Context) error {
+	scanner := bufio.NewScanner(c.Request.Body)
+	scanner.Buffer(make([]byte, 64*1024), maxImportLine)
+	w, err := render.Stream(c, "application/x-ndjson")
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+
+	var line int
+	summary := importSummary{Status: "done"}
+	for scanner.Scan() {
+		line++
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+		result := importUser(c.Request.Context(), h.users, raw)
+		result.Line = line
+		if result.Status == "created" {
+			summary.Created++
+		} else {
+			summary.Failed++
+		}
+		_ = enc.Encode(result)
+		w.Flush()
+	}
+	if err := scanner.Err(); err != nil {
+		summary.Status = "aborted"
+		summary.Failed++
+		_ = enc.Encode(importResult{Line: line + 1, Status: "failed", Error: err.Error()})
+	}
+	_ = enc.Encode(summary)
+	return nil
+}
+
+// importUser validates and creates the user of one NDJSON line
+func importUser(ctx context.Context, users repository.Users, raw []byte) importResult {
+	var req userRequest
+	if err := json.Unmarshal(raw, &req); err != nil {
+		return importResult{Status: "failed", Error: "invalid JSON: " + err.Error(), Fields: validation.Fields(err)}
+	}
+	if err := binding.Validator.ValidateStruct(&req); err != nil {
+		return importResult{Status: "failed", Error: "invalid fields", Fields: validation.Fields(err)}
+	}
+	user := models.User{Role: models.RoleUser}
+	if err := req.apply(&user); err != nil {
+		return importResult{Status: "failed", Error: "invalid fields", Fields: validation.Fields(err)}
+	}
+	switch err := dberr.Translate(users.Create(ctx, &user)); {
+	case err == nil:
+		return importResult{Status: "created", ID: user.ID}
+	case errors.Is(err, dberr.ErrConflict):
+		return importResult{Status: "failed", Error: "User already exists"}
+	case errors.Is(err, dberr.ErrConstraint):
+		return importResult{Status: "failed", Error: "User violates a database constraint"}
+	default:
+		return importResult{Status: "failed", Error: "Could not create user"}
+	}
+}
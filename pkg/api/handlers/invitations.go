@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/rkgcloud/crud/pkg/api/render"
+	"github.com/rkgcloud/crud/pkg/database"
+	"github.com/rkgcloud/crud/pkg/dberr"
+	"github.com/rkgcloud/crud/pkg/models"
+	"github.com/rkgcloud/crud/pkg/outbox"
+	"github.com/rkgcloud/crud/pkg/problem"
+	"github.com/rkgcloud/crud/pkg/validation"
+	"github.com/rkgcloud/crud/pkg/verification"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// acceptInvitationRequest is the body of an invitation acceptance
+type acceptInvitationRequest struct {
+	Token string `json:"token" binding:"required"`
+	Name  string `json:"name" binding:"required"`
+	Age   int    `json:"age" binding:"required"`
+}
+
+// invitationRequest is the body of an invitation
+type invitationRequest struct {
+	Email string `json:"email" binding:"required,email_address"`
+	Role  string `json:"role" binding:"omitempty,oneof=user admin"`
+}
+
+// invitationResponse is an invitation as returned by the API
+type invitationResponse struct {
+	ID         uint       `json:"id"`
+	Email      string     `json:"email"`
+	Role       string     `json:"role"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	AcceptedAt *time.Time `json:"accepted_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func newInvitationResponse(i models.Invitation) invitationResponse {
+	return invitationResponse{
+		ID:         i.ID,
+		Email:      i.Email,
+		Role:       i.Role,
+		ExpiresAt:  i.ExpiresAt,
+		AcceptedAt: i.AcceptedAt,
+		CreatedAt:  i.CreatedAt,
+	}
+}
+
+// errUserExists is returned when an invited address already belongs to a user
+var errUserExists = errors.New("user already exists")
+
+// InvitationController serves the invitation routes
+type InvitationController struct {
+	verifier *verification.Verifier
+	mailer   Mailer
+	baseURL  string
+}
+
+// NewInvitationController creates an InvitationController sending invite
+// links on baseURL signed by verifier through mailer
+func NewInvitationController(verifier *verification.Verifier, mailer Mailer, baseURL string) *InvitationController {
+	return &InvitationController{verifier: verifier, mailer: mailer, baseURL: baseURL}
+}
+
+// Create invites someone by email and sends them a signed invite link
+func (h *InvitationController) Create(c *gin.Context) error {
+	db := database.Get(c)
+	var req invitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		return problem.Validation(err)
+	}
+	email, err := validation.NormalizeEmail(req.Email)
+	if err != nil {
+		return problem.Validation(err)
+	}
+	invitation := models.Invitation{Email: email, Role: req.Role, ExpiresAt: time.Now().Add(h.verifier.TTL())}
+	if invitation.Role == "" {
+		invitation.Role = models.RoleUser
+	}
+
+	var count int64
+	if err := db.Model(&models.User{}).Where("email = ?", invitation.Email).Count(&count).Error; err != nil {
+		return dbError(err, "invitation", "create")
+	}
+	if count > 0 {
+		return problem.New(http.StatusConflict, "User already exists")
+	}
+	if err := db.Create(&invitation).Error; err != nil {
+		return dbError(err, "invitation", "create")
+	}
+	if err := h.sendInvitation(invitation); err != nil {
+		return problem.New(http.StatusServiceUnavailable, "Invitation created but could not be sent, resend it later")
+	}
+	return render.One(c, http.StatusCreated, newInvitationResponse(invitation))
+}
+
+// List retrieves all invitations
+func (h *InvitationController) List(c *gin.Context) error {
+	db := database.Get(c)
+	page, err := render.ParsePage(c)
+	if err != nil {
+		return problem.Validation(err)
+	}
+	var invitations []models.Invitation
+	total, err := paginate(db, page, &invitations)
+	if err != nil {
+		return dbError(err, "invitations", "retrieve")
+	}
+	resp := make([]invitationResponse, 0, len(invitations))
+	for _, invitation := range invitations {
+		resp = append(resp, newInvitationResponse(invitation))
+	}
+	return render.List(c, resp, page, total)
+}
+
+// Resend extends a pending invitation and sends a fresh invite link
+func (h *InvitationController) Resend(c *gin.Context) error {
+	db := database.Get(c)
+	invitation, err := findInvitation(c, db)
+	if err != nil {
+		return err
+	}
+	if invitation.AcceptedAt != nil {
+		return problem.New(http.StatusConflict, "Invitation already accepted")
+	}
+	invitation.ExpiresAt = time.Now().Add(h.verifier.TTL())
+	if err := db.Save(&invitation).Error; err != nil {
+		return dbError(err, "invitation", "update")
+	}
+	if err := h.sendInvitation(invitation); err != nil {
+		return problem.New(http.StatusServiceUnavailable, "Could not send invitation")
+	}
+	return render.One(c, http.StatusOK, newInvitationResponse(invitation))
+}
+
+// Delete revokes an invitation
+func (h *InvitationController) Delete(c *gin.Context) error {
+	db := database.Get(c)
+	invitation, err := findInvitation(c, db)
+	if err != nil {
+		return err
+	}
+	if err := db.Unscoped().Delete(&invitation).Error; err != nil {
+		return dbError(err, "invitation", "delete")
+	}
+	return render.One(c, http.StatusOK, gin.H{"message": "Invitation deleted"})
+}
+
+// Pending returns the invitation of the token query parameter, the link sent
+// by email, so the acceptance form can show who is invited and as what. The
+// invitation is accepted by POSTing the token with the user's name and age.
+func (h *InvitationController) Pending(c *gin.Context) error {
+	id, email, err := h.verifier.Verify(c.Query("token"))
+	if err != nil {
+		return problem.New(http.StatusBadRequest, err.Error())
+	}
+	var invitation models.Invitation
+	if err := database.Get(c).First(&invitation, id).Error; err != nil {
+		return dbError(err, "invitation", "retrieve")
+	}
+	if invitation.Email != email || invitation.AcceptedAt != nil || time.Now().After(invitation.ExpiresAt) {
+		return problem.New(http.StatusBadRequest, "Invitation is no longer valid")
+	}
+	return render.One(c, http.StatusOK, newInvitationResponse(invitation))
+}
+
+// Accept creates the invited user with the invited role
+func (h *InvitationController) Accept(c *gin.Context) error {
+	db := database.Get(c)
+	var req acceptInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		return problem.Validation(err)
+	}
+	id, email, err := h.verifier.Verify(req.Token)
+	if err != nil {
+		return problem.New(http.StatusBadRequest, err.Error())
+	}
+
+	var user models.User
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var invitation models.Invitation
+		if err := tx.First(&invitation, id).Error; err != nil {
+			return err
+		}
+		if invitation.Email != email || invitation.AcceptedAt != nil || time.Now().After(invitation.ExpiresAt) {
+			return verification.ErrInvalidToken
+		}
+		// The address may have signed up since it was invited
+		var count int64
+		if err := tx.Model(&models.User{}).Where("email = ?", invitation.Email).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return errUserExists
+		}
+		user = models.User{
+			Name:  req.Name,
+			Email: invitation.Email,
+			Age:   req.Age,
+			Role:  invitation.Role,
+			// Following the invite link proves ownership of the address
+			Verified: true,
+		}
+		if err := tx.Create(&user).Error; err != nil {
+			if errors.Is(dberr.Translate(err), dberr.ErrConflict) {
+				return errUserExists
+			}
+			return err
+		}
+		if err := outbox.Enqueue(tx, outbox.UserCreated, user); err != nil {
+			return err
+		}
+		now := time.Now()
+		invitation.AcceptedAt = &now
+		return tx.Save(&invitation).Error
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, verification.ErrInvalidToken):
+			return problem.New(http.StatusBadRequest, "Invitation is no longer valid")
+		case errors.Is(err, errUserExists):
+			return problem.New(http.StatusConflict, "User already exists")
+		}
+		return dbError(err, "invitation", "accept")
+	}
+	return render.One(c, http.StatusCreated, newUserResponse(user))
+}
+
+// findInvitation loads the invitation from the id path parameter, failing
+// with a 404 when missing
+func findInvitation(c *gin.Context, db *gorm.DB) (models.Invitation, error) {
+	var invitation models.Invitation
+	id, err := paramID(c, "id", "invitation")
+	if err != nil {
+		return invitation, err
+	}
+	if err := db.First(&invitation, id).Error; err != nil {
+		return invitation, dbError(err, "invitation", "retrieve")
+	}
+	return invitation, nil
+}
+
+// sendInvitation emails the invite link for invitation
+func (h *InvitationController) sendInvitation(invitation models.Invitation) error {
+	link := publicURL(h.baseURL, "/invitations/accept", url.Values{"token": {h.verifier.Token(invitation.ID, invitation.Email)}})
+	if err := h.mailer.SendInvitation(invitation.Email, invitation.Role, link, invitation.ExpiresAt); err != nil {
+		log.Printf("failed to queue invitation %d: %v\n", invitation.ID, err)
+		return err
+	}
+	return nil
+}
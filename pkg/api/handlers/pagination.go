@@ -0,0 +1,14 @@
+package handlers
+
+import (
+	"github.com/rkgcloud/crud/pkg/api/render"
+	"github.com/rkgcloud/crud/pkg/repository"
+
+	"gorm.io/gorm"
+)
+
+// paginate loads one page of the records matched by query into dest, ordered
+// by ID, and returns the total number of matching records
+func paginate[T any](query *gorm.DB, page render.Page, dest *[]T) (int64, error) {
+	return repository.Paginate(query, page.Offset(), page.Limit, dest)
+}
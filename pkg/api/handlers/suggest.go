@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/rkgcloud/crud/pkg/api/render"
+	"github.com/rkgcloud/crud/pkg/database"
+	"github.com/rkgcloud/crud/pkg/problem"
+	"github.com/rkgcloud/crud/pkg/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// suggestLimit is the number of users Suggest returns
+const suggestLimit = 10
+
+// userSuggestion is a user offered by a typeahead widget
+type userSuggestion struct {
+	ID    uint   `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+// Suggest returns the users whose name or email starts with the q query
+// parameter, so forms can offer existing users instead of taking raw IDs. It
+// reveals which emails exist and must only be routed for admins.
+func (h *UserController) Suggest(c *gin.Context) error {
+	q := strings.TrimSpace(c.Query("q"))
+	if q == "" {
+		return problem.Validation(&validation.FieldError{Field: "q", Rule: "required", Message: "is required"})
+	}
+	users, err := h.users.Suggest(database.Context(c), q, suggestLimit)
+	if err != nil {
+		return dbError(err, "users", "retrieve")
+	}
+	resp := make([]userSuggestion, 0, len(users))
+	for _, user := range users {
+		resp = append(resp, userSuggestion{ID: user.ID, Name: user.Name, Email: user.Email})
+	}
+	return render.One(c, http.StatusOK, resp)
+}
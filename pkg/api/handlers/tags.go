@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/rkgcloud/crud/pkg/api/render"
+	"github.com/rkgcloud/crud/pkg/database"
+	"github.com/rkgcloud/crud/pkg/models"
+	"github.com/rkgcloud/crud/pkg/problem"
+	"github.com/rkgcloud/crud/pkg/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// tagPattern is the form of a tag once lowercased, such as vip or beta-tester
+var tagPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,49}$`)
+
+// tagRequest is the body of a request adding a tag
+type tagRequest struct {
+	Tag string `json:"tag" binding:"required"`
+}
+
+// parseTag normalizes tag to lowercase and checks its form, reporting it as
+// the field named field
+func parseTag(field, tag string) (string, error) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if !tagPattern.MatchString(tag) {
+		return "", &validation.FieldError{Field: field, Rule: "tag", Message: "must be 1 to 50 letters, digits, - or _"}
+	}
+	return tag, nil
+}
+
+// Tags lists the tags of a user
+func (h *UserController) Tags(c *gin.Context) error {
+	user, err := findUser(c, h.users)
+	if err != nil {
+		return err
+	}
+	return h.renderTags(c, user)
+}
+
+// AddTag tags a user and responds with all of its tags
+func (h *UserController) AddTag(c *gin.Context) error {
+	user, err := findUser(c, h.users)
+	if err != nil {
+		return err
+	}
+	var req tagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		return problem.Validation(err)
+	}
+	tag, err := parseTag("tag", req.Tag)
+	if err != nil {
+		return problem.Validation(err)
+	}
+	if err := h.users.AddTag(database.Context(c), user, tag); err != nil {
+		return dbError(err, "tag", "add")
+	}
+	return h.renderTags(c, user)
+}
+
+// RemoveTag removes a tag from a user and responds with its remaining tags
+func (h *UserController) RemoveTag(c *gin.Context) error {
+	user, err := findUser(c, h.users)
+	if err != nil {
+		return err
+	}
+	if err := h.users.RemoveTag(database.Context(c), user, strings.ToLower(c.Param("tag"))); err != nil {
+		return dbError(err, "tag", "remove")
+	}
+	return h.renderTags(c, user)
+}
+
+// renderTags responds with the tags of user
+func (h *UserController) renderTags(c *gin.Context, user models.User) error {
+	tags, err := h.users.Tags(database.Context(c), user)
+	if err != nil {
+		return dbError(err, "tags", "retrieve")
+	}
+	return render.One(c, http.StatusOK, tags)
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/rkgcloud/crud/pkg/models"
+	"github.com/rkgcloud/crud/pkg/testing/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestResendVerification(t *testing.T) {
+	users := mocks.NewUsers(t)
+	mailer := mocks.NewMailer(t)
+	users.EXPECT().Get(mock.Anything, uint(7)).Return(models.User{Model: gorm.Model{ID: 7}, Name: "Ann", Email: "ann@example.com"}, nil)
+	var link string
+	mailer.EXPECT().SendVerification("ann@example.com", "Ann", mock.Anything, mock.Anything).
+		Run(func(_, _, l string, _ time.Time) { link = l }).
+		Return(nil)
+
+	w := send(newUserEngine(t, users, mailer), http.MethodPost, "/users/7/verification", "")
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+
+	// The link carries a token for the user and their current address
+	u, err := url.Parse(link)
+	require.NoError(t, err)
+	id, email, err := testVerifier.Verify(u.Query().Get("token"))
+	require.NoError(t, err)
+	assert.Equal(t, uint(7), id)
+	assert.Equal(t, "ann@example.com", email)
+}
+
+func TestResendVerificationOfVerifiedUser(t *testing.T) {
+	users := mocks.NewUsers(t)
+	users.EXPECT().Get(mock.Anything, uint(7)).Return(models.User{Model: gorm.Model{ID: 7}, Email: "ann@example.com", Verified: true}, nil)
+
+	// The mailer expects no call
+	w := send(newUserEngine(t, users, mocks.NewMailer(t)), http.MethodPost, "/users/7/verification", "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestResendVerificationMailFailure(t *testing.T) {
+	users := mocks.NewUsers(t)
+	mailer := mocks.NewMailer(t)
+	users.EXPECT().Get(mock.Anything, uint(7)).Return(models.User{Model: gorm.Model{ID: 7}, Email: "ann@example.com"}, nil)
+	mailer.EXPECT().SendVerification(mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(errors.New("relay down"))
+
+	w := send(newUserEngine(t, users, mailer), http.MethodPost, "/users/7/verification", "")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), "Could not send verification")
+}
+
+func TestVerify(t *testing.T) {
+	users := mocks.NewUsers(t)
+	users.EXPECT().Get(mock.Anything, uint(7)).Return(models.User{Model: gorm.Model{ID: 7}, Email: "ann@example.com"}, nil)
+	users.EXPECT().MarkVerified(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, user *models.User) error {
+		user.Verified = true
+		return nil
+	})
+
+	w := send(newUserEngine(t, users, mocks.NewMailer(t)), http.MethodGet, "/users/verify?token="+url.QueryEscape(testVerifier.Token(7, "ann@example.com")), "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.True(t, decodeUser(t, w).Verified)
+}
+
+func TestVerifyTokenOfPreviousAddress(t *testing.T) {
+	users := mocks.NewUsers(t)
+	users.EXPECT().Get(mock.Anything, uint(7)).Return(models.User{Model: gorm.Model{ID: 7}, Email: "ann.b@example.com"}, nil)
+
+	// The repository expects no MarkVerified call
+	w := send(newUserEngine(t, users, mocks.NewMailer(t)), http.MethodGet, "/users/verify?token="+url.QueryEscape(testVerifier.Token(7, "ann@example.com")), "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestVerifyInvalidToken(t *testing.T) {
+	// Neither mock expects a call
+	w := send(newUserEngine(t, mocks.NewUsers(t), mocks.NewMailer(t)), http.MethodGet, "/users/verify?token=forged", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,32 @@
+package render
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandlerFunc is a handler that either responds through this package or
+// returns the error to answer the request with, and never both
+type HandlerFunc func(c *gin.Context) error
+
+// Handle adapts h to gin. The error h returns is answered as problem details
+// by problem.Handler, so every request gets exactly one response. Returning
+// an error after responding, or returning nil without responding, is a bug:
+// the first is logged and the second answered with a 500.
+func Handle(h HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := h(c)
+		switch {
+		case err != nil && c.Writer.Written():
+			log.Printf("render: %s %s failed after responding: %v\n", c.Request.Method, c.Request.URL.Path, err)
+		case err != nil:
+			_ = c.Error(err)
+			c.Abort()
+		case !c.Writer.Written():
+			_ = c.Error(fmt.Errorf("render: %s %s returned without responding", c.Request.Method, c.Request.URL.Path))
+			c.Abort()
+		}
+	}
+}
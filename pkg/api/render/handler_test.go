@@ -0,0 +1,85 @@
+package render
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rkgcloud/crud/pkg/problem"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func serve(h HandlerFunc) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(problem.Handler())
+	r.GET("/", Handle(h))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	return w
+}
+
+func TestHandleRespondsOnce(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler HandlerFunc
+		status  int
+		body    string
+	}{
+		{
+			name:    "rendered",
+			handler: func(c *gin.Context) error { return One(c, http.StatusCreated, "ok") },
+			status:  http.StatusCreated,
+			body:    `{"data":"ok"}`,
+		},
+		{
+			name:    "problem",
+			handler: func(c *gin.Context) error { return problem.New(http.StatusNotFound, "User not found") },
+			status:  http.StatusNotFound,
+			body:    `"detail":"User not found"`,
+		},
+		{
+			name:    "other error",
+			handler: func(c *gin.Context) error { return errors.New("boom") },
+			status:  http.StatusInternalServerError,
+		},
+		{
+			name:    "no response",
+			handler: func(c *gin.Context) error { return nil },
+			status:  http.StatusInternalServerError,
+		},
+		{
+			name: "second render",
+			handler: func(c *gin.Context) error {
+				_ = One(c, http.StatusOK, "first")
+				return One(c, http.StatusOK, "second")
+			},
+			status: http.StatusOK,
+			body:   `{"data":"first"}`,
+		},
+		{
+			name: "error after rendering",
+			handler: func(c *gin.Context) error {
+				_ = One(c, http.StatusOK, "first")
+				return problem.New(http.StatusConflict, "too late")
+			},
+			status: http.StatusOK,
+			body:   `{"data":"first"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(tt.handler)
+			assert.Equal(t, tt.status, w.Code)
+			switch {
+			case tt.status < http.StatusBadRequest:
+				assert.JSONEq(t, tt.body, w.Body.String())
+			case tt.body != "":
+				assert.Contains(t, w.Body.String(), tt.body)
+			}
+		})
+	}
+}
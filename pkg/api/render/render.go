@@ -0,0 +1,179 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/rkgcloud/crud/pkg/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Pagination defaults and bounds
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// ErrResponded is returned when rendering a second response to a request
+var ErrResponded = errors.New("the request was already answered")
+
+// Envelope wraps every successful API response
+type Envelope struct {
+	Data  any    `json:"data"`
+	Meta  *Meta  `json:"meta,omitempty"`
+	Links *Links `json:"links,omitempty"`
+}
+
+// Meta describes the page of a list response
+type Meta struct {
+	Page  int   `json:"page"`
+	Limit int   `json:"limit"`
+	Total int64 `json:"total"`
+}
+
+// Links point to the neighbouring pages of a list response
+type Links struct {
+	Self  string `json:"self"`
+	First string `json:"first"`
+	Last  string `json:"last"`
+	Prev  string `json:"prev,omitempty"`
+	Next  string `json:"next,omitempty"`
+}
+
+// Page is the requested page of a list
+type Page struct {
+	Number int
+	Limit  int
+}
+
+// Offset is the number of items before the page
+func (p Page) Offset() int {
+	return (p.Number - 1) * p.Limit
+}
+
+// ParsePage reads the page and limit query parameters, defaulting to the
+// first page of DefaultLimit items
+func ParsePage(c *gin.Context) (Page, error) {
+	page := Page{Number: 1, Limit: DefaultLimit}
+	if value := c.Query("page"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return page, &validation.FieldError{Field: "page", Rule: "min", Message: "must be a positive integer"}
+		}
+		page.Number = n
+	}
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > MaxLimit {
+			return page, &validation.FieldError{Field: "limit", Rule: "max", Message: fmt.Sprintf("must be between 1 and %d", MaxLimit)}
+		}
+		page.Limit = n
+	}
+	return page, nil
+}
+
+// One responds with a single resource
+func One(c *gin.Context, status int, data any) error {
+	if err := responded(c); err != nil {
+		return err
+	}
+	c.PureJSON(status, Envelope{Data: data})
+	return nil
+}
+
+// List responds with one page of a list of total items
+func List[T any](c *gin.Context, items []T, page Page, total int64) error {
+	if err := responded(c); err != nil {
+		return err
+	}
+	if items == nil {
+		items = []T{}
+	}
+	last := int((total + int64(page.Limit) - 1) / int64(page.Limit))
+	if last < 1 {
+		last = 1
+	}
+	links := &Links{
+		Self:  pageURL(c, page.Number, page.Limit),
+		First: pageURL(c, 1, page.Limit),
+		Last:  pageURL(c, last, page.Limit),
+	}
+	if page.Number > 1 {
+		links.Prev = pageURL(c, min(page.Number-1, last), page.Limit)
+	}
+	if page.Number < last {
+		links.Next = pageURL(c, page.Number+1, page.Limit)
+	}
+	// PureJSON keeps the & of the links unescaped
+	c.PureJSON(http.StatusOK, Envelope{
+		Data:  items,
+		Meta:  &Meta{Page: page.Number, Limit: page.Limit, Total: total},
+		Links: links,
+	})
+	return nil
+}
+
+// JSON responds with body as is, without the envelope, for the probes and
+// tooling that expect their own shapes
+func JSON(c *gin.Context, status int, body any) error {
+	if err := responded(c); err != nil {
+		return err
+	}
+	c.JSON(status, body)
+	return nil
+}
+
+// Redirect responds with a redirection to location
+func Redirect(c *gin.Context, status int, location string) error {
+	if err := responded(c); err != nil {
+		return err
+	}
+	c.Redirect(status, location)
+	return nil
+}
+
+// Reader responds with the content of r, sent as it is read
+func Reader(c *gin.Context, contentType string, r io.Reader, headers map[string]string) error {
+	if err := responded(c); err != nil {
+		return err
+	}
+	c.DataFromReader(http.StatusOK, -1, contentType, r, headers)
+	return nil
+}
+
+// Stream starts a 200 response of contentType and returns the writer to
+// stream its body to. Errors met once streaming started can no longer be
+// reported to the client.
+func Stream(c *gin.Context, contentType string) (gin.ResponseWriter, error) {
+	if err := responded(c); err != nil {
+		return nil, err
+	}
+	c.Header("Content-Type", contentType)
+	c.Status(http.StatusOK)
+	c.Writer.WriteHeaderNow()
+	return c.Writer, nil
+}
+
+// responded returns ErrResponded when the request was aborted or already
+// answered, in which case a second response must not be written over the
+// problem or the first one
+func responded(c *gin.Context) error {
+	if c.IsAborted() || c.Writer.Written() {
+		return ErrResponded
+	}
+	return nil
+}
+
+// pageURL is the request path and query with page and limit replaced
+func pageURL(c *gin.Context, number, limit int) string {
+	query := c.Request.URL.Query()
+	query.Set("page", strconv.Itoa(number))
+	query.Set("limit", strconv.Itoa(limit))
+	u := url.URL{Path: c.Request.URL.Path, RawQuery: query.Encode()}
+	return u.String()
+}
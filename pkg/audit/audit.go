@@ -0,0 +1,108 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/rkgcloud/crud/pkg/models"
+
+	"gorm.io/gorm"
+)
+
+// Actors of entries not recorded on behalf of a user
+const (
+	// ActorSystem records background tasks
+	ActorSystem = "system"
+	// ActorCLI records operators running crud commands
+	ActorCLI = "cli"
+	// ActorAdmin records requests authenticated with the admin token
+	ActorAdmin = "admin"
+)
+
+// chainLock is the advisory lock serializing the chaining of entries
+const chainLock = 0x61756469
+
+// Record appends an audit entry. Pass the transaction performing the audited
+// change so the entry is only kept if the change is committed.
+//
+// Every entry is chained to the previous one by hash, so that modified or
+// deleted entries can be detected with Verify. The hashes are plain SHA-256
+// without a key, so they hold against accidents and tampering through the
+// application, not against someone with write access to the database, who can
+// rewrite an entry and recompute the hashes of every entry after it.
+func Record(tx *gorm.DB, actor, action, target string, details any) error {
+	data, err := json.Marshal(details)
+	if err != nil {
+		return fmt.Errorf("encoding audit details: %w", err)
+	}
+	// A nested transaction is a savepoint, so the lock is held until the
+	// audited change commits
+	return tx.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", chainLock).Error; err != nil {
+			return fmt.Errorf("locking audit log: %w", err)
+		}
+		var prev []string
+		if err := tx.Model(&models.AuditEntry{}).Where("hash <> ''").Order("id DESC").Limit(1).Pluck("hash", &prev).Error; err != nil {
+			return err
+		}
+		entry := models.AuditEntry{
+			// Postgres keeps microseconds, which the hash must match
+			CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+			Actor:     actor,
+			Action:    action,
+			Target:    target,
+			Details:   data,
+		}
+		if len(prev) > 0 {
+			entry.PrevHash = prev[0]
+		}
+		if err := seal(&entry); err != nil {
+			return err
+		}
+		return tx.Create(&entry).Error
+	})
+}
+
+// seal hashes the details of entry and chains it to its PrevHash
+func seal(entry *models.AuditEntry) error {
+	var err error
+	if entry.DetailsHash, err = detailsHash(entry.Details); err != nil {
+		return err
+	}
+	entry.Hash = entryHash(*entry)
+	return nil
+}
+
+// detailsHash hashes details in a canonical encoding, as Postgres does not
+// keep the key order and spacing of jsonb values
+func detailsHash(details json.RawMessage) (string, error) {
+	var value any
+	if len(details) > 0 {
+		if err := json.Unmarshal(details, &value); err != nil {
+			return "", fmt.Errorf("decoding audit details: %w", err)
+		}
+	}
+	canonical, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// entryHash hashes the fields of entry together with the previous hash
+func entryHash(entry models.AuditEntry) string {
+	fields, _ := json.Marshal([]string{
+		entry.PrevHash,
+		entry.CreatedAt.UTC().Format(time.RFC3339Nano),
+		entry.Actor,
+		entry.Action,
+		entry.Target,
+		entry.DetailsHash,
+	})
+	sum := sha256.Sum256(fields)
+	return hex.EncodeToString(sum[:])
+}
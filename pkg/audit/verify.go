@@ -0,0 +1,94 @@
+package audit
+
+import (
+	"context"
+
+	"github.com/rkgcloud/crud/pkg/models"
+
+	"gorm.io/gorm"
+)
+
+// verifyBatchSize is the number of entries read per query while verifying
+const verifyBatchSize = 500
+
+// Problem is an audit entry failing verification
+type Problem struct {
+	ID     uint   `json:"id"`
+	Reason string `json:"reason"`
+}
+
+// Report is the result of Verify
+type Report struct {
+	// Entries is the number of chained entries checked
+	Entries int `json:"entries"`
+	// Unchained is the number of entries recorded before entries were chained
+	Unchained int `json:"unchained"`
+	// Erased is the number of entries whose personal details were removed by
+	// an erasure of their target
+	Erased int `json:"erased"`
+	// Head is the hash of the latest entry. The chain has no key, so anyone
+	// able to write to the database can rebuild it after tampering; only a
+	// Head kept outside the database, such as in an append-only store, proves
+	// that the entries up to it are unchanged and none following it were
+	// deleted.
+	Head     string    `json:"head"`
+	Problems []Problem `json:"problems"`
+}
+
+// Valid reports whether no entry failed verification
+func (r Report) Valid() bool {
+	return len(r.Problems) == 0
+}
+
+// Verify walks the audit log in order and reports the entries that were
+// modified, deleted or inserted out of the chain
+func Verify(ctx context.Context, db *gorm.DB) (Report, error) {
+	report := Report{Problems: []Problem{}}
+	db = db.WithContext(ctx)
+	// Erasures legitimately remove personal details from the entries of their target
+	var erased []string
+	if err := db.Model(&models.AuditEntry{}).Where("action = ?", "user.erased").Distinct().Pluck("target", &erased).Error; err != nil {
+		return report, err
+	}
+	erasedTargets := map[string]bool{}
+	for _, target := range erased {
+		erasedTargets[target] = true
+	}
+
+	var batch []models.AuditEntry
+	err := db.Order("id").FindInBatches(&batch, verifyBatchSize, func(tx *gorm.DB, _ int) error {
+		for _, entry := range batch {
+			report.check(entry, erasedTargets)
+		}
+		return nil
+	}).Error
+	return report, err
+}
+
+// check verifies entry, the next one in ID order, against the entries checked
+// before it
+func (r *Report) check(entry models.AuditEntry, erasedTargets map[string]bool) {
+	if entry.Hash == "" {
+		if r.Head == "" {
+			r.Unchained++
+		} else {
+			r.Problems = append(r.Problems, Problem{entry.ID, "entry is not chained"})
+		}
+		return
+	}
+	r.Entries++
+	if entry.PrevHash != r.Head {
+		r.Problems = append(r.Problems, Problem{entry.ID, "previous entry was deleted or modified"})
+	}
+	if entryHash(entry) != entry.Hash {
+		r.Problems = append(r.Problems, Problem{entry.ID, "entry was modified"})
+	}
+	if hash, err := detailsHash(entry.Details); err != nil || hash != entry.DetailsHash {
+		if erasedTargets[entry.Target] {
+			r.Erased++
+		} else {
+			r.Problems = append(r.Problems, Problem{entry.ID, "details were modified"})
+		}
+	}
+	r.Head = entry.Hash
+}
@@ -0,0 +1,163 @@
+package audit
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/rkgcloud/crud/pkg/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// chainOf seals entries into a chain as Record does, numbering them from 1
+func chainOf(t *testing.T, entries ...models.AuditEntry) []models.AuditEntry {
+	t.Helper()
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	prev := ""
+	for i := range entries {
+		entries[i].ID = uint(i + 1)
+		entries[i].CreatedAt = start.Add(time.Duration(i) * time.Minute)
+		entries[i].PrevHash = prev
+		require.NoError(t, seal(&entries[i]))
+		prev = entries[i].Hash
+	}
+	return entries
+}
+
+func entry(action, target, details string) models.AuditEntry {
+	return models.AuditEntry{Actor: ActorAdmin, Action: action, Target: target, Details: json.RawMessage(details)}
+}
+
+// verifyEntries checks entries in order as Verify does with the stored log
+func verifyEntries(entries []models.AuditEntry) Report {
+	report := Report{Problems: []Problem{}}
+	erasedTargets := map[string]bool{}
+	for _, e := range entries {
+		if e.Action == "user.erased" {
+			erasedTargets[e.Target] = true
+		}
+	}
+	for _, e := range entries {
+		report.check(e, erasedTargets)
+	}
+	return report
+}
+
+func testLog(t *testing.T) []models.AuditEntry {
+	return chainOf(t,
+		entry("user.created", "user:1", `{"name": "Ann", "email": "ann@example.com"}`),
+		entry("user.updated", "user:1", `{"name": "Ann Lee"}`),
+		entry("user.created", "user:2", `{"name": "Bob", "email": "bob@example.com"}`),
+		entry("user.deleted", "user:2", `{}`),
+	)
+}
+
+func TestVerifyIntactLog(t *testing.T) {
+	log := testLog(t)
+	report := verifyEntries(log)
+	assert.True(t, report.Valid(), report.Problems)
+	assert.Equal(t, 4, report.Entries)
+	assert.Equal(t, log[3].Hash, report.Head)
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func([]models.AuditEntry) []models.AuditEntry
+		want   []Problem
+	}{
+		{
+			name: "modified entry",
+			tamper: func(log []models.AuditEntry) []models.AuditEntry {
+				log[1].Actor = "someone-else"
+				return log
+			},
+			want: []Problem{{2, "entry was modified"}},
+		},
+		{
+			name: "modified details",
+			tamper: func(log []models.AuditEntry) []models.AuditEntry {
+				log[2].Details = json.RawMessage(`{"name": "Mallory", "email": "bob@example.com"}`)
+				return log
+			},
+			want: []Problem{{3, "details were modified"}},
+		},
+		{
+			name: "deleted middle entry",
+			tamper: func(log []models.AuditEntry) []models.AuditEntry {
+				return slices.Delete(log, 1, 2)
+			},
+			want: []Problem{{3, "previous entry was deleted or modified"}},
+		},
+		{
+			name: "unchained entry inserted",
+			tamper: func(log []models.AuditEntry) []models.AuditEntry {
+				forged := entry("user.deleted", "user:1", `{}`)
+				forged.ID = 5
+				return slices.Insert(log, 2, forged)
+			},
+			want: []Problem{{5, "entry is not chained"}},
+		},
+		{
+			name: "sealed entry inserted out of the chain",
+			tamper: func(log []models.AuditEntry) []models.AuditEntry {
+				forged := entry("user.deleted", "user:1", `{}`)
+				forged.ID, forged.CreatedAt, forged.PrevHash = 5, log[1].CreatedAt, log[1].Hash
+				require.NoError(t, seal(&forged))
+				return slices.Insert(log, 2, forged)
+			},
+			want: []Problem{{3, "previous entry was deleted or modified"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := verifyEntries(tt.tamper(testLog(t)))
+			assert.False(t, report.Valid())
+			assert.Equal(t, tt.want, report.Problems)
+		})
+	}
+}
+
+func TestVerifyAcceptsErasure(t *testing.T) {
+	log := append(testLog(t), entry("user.erased", "user:1", `{"user_id": 1}`))
+	log = chainOf(t, log...)
+	// Erasing user 1 removes the personal details of its entries, as
+	// repository.Users.Erase does
+	log[0].Details = json.RawMessage(`{}`)
+	log[1].Details = json.RawMessage(`{}`)
+
+	report := verifyEntries(log)
+	assert.True(t, report.Valid(), report.Problems)
+	assert.Equal(t, 2, report.Erased)
+
+	// Details of other targets are still protected
+	log[2].Details = json.RawMessage(`{}`)
+	report = verifyEntries(log)
+	assert.Equal(t, []Problem{{3, "details were modified"}}, report.Problems)
+}
+
+func TestVerifyUnchainedEntriesBeforeTheChain(t *testing.T) {
+	legacy := entry("user.created", "user:9", `{}`)
+	log := append([]models.AuditEntry{legacy}, testLog(t)...)
+	report := verifyEntries(log)
+	assert.True(t, report.Valid(), report.Problems)
+	assert.Equal(t, 1, report.Unchained)
+	assert.Equal(t, 4, report.Entries)
+}
+
+// The chain has no key: a rewritten log whose hashes were recomputed verifies,
+// and only a head kept outside the database reveals it
+func TestVerifyRebuiltChainNeedsExternalHead(t *testing.T) {
+	log := testLog(t)
+	head := verifyEntries(log).Head
+
+	rebuilt := testLog(t)
+	rebuilt[1].Details = json.RawMessage(`{"name": "Mallory"}`)
+	rebuilt = chainOf(t, rebuilt...)
+	report := verifyEntries(rebuilt)
+	assert.True(t, report.Valid())
+	assert.NotEqual(t, head, report.Head)
+}
@@ -0,0 +1,78 @@
+// Package captcha checks the responses of hCaptcha and Cloudflare Turnstile
+// widgets with their provider. Both providers share the same verification API.
+package captcha
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/rkgcloud/crud/pkg/config"
+	"github.com/rkgcloud/crud/pkg/httpclient"
+)
+
+// endpoints are the verification URLs of the providers
+var endpoints = map[string]string{
+	config.CaptchaHCaptcha:  "https://api.hcaptcha.com/siteverify",
+	config.CaptchaTurnstile: "https://challenges.cloudflare.com/turnstile/v0/siteverify",
+}
+
+// ErrRejected is returned when the provider does not accept a response
+var ErrRejected = errors.New("captcha rejected")
+
+// Verifier checks widget responses with the configured provider
+type Verifier struct {
+	url    string
+	secret string
+	client *httpclient.Client
+}
+
+// New creates a Verifier for the provider of cfg, or returns nil when
+// captchas are disabled
+func New(cfg config.BotGuardConfig) *Verifier {
+	if cfg.CaptchaProvider == "" {
+		return nil
+	}
+	return &Verifier{
+		url:    endpoints[cfg.CaptchaProvider],
+		secret: cfg.CaptchaSecret,
+		client: httpclient.New(5*time.Second, 1),
+	}
+}
+
+// verifyResponse is the answer of the verification API
+type verifyResponse struct {
+	Success    bool     `json:"success"`
+	ErrorCodes []string `json:"error-codes"`
+}
+
+// Verify checks response, the token the widget produced for the client at
+// remoteIP. It returns ErrRejected when the provider refuses it and another
+// error when the provider could not be asked.
+func (v *Verifier) Verify(ctx context.Context, response, remoteIP string) error {
+	if response == "" {
+		return fmt.Errorf("%w: no response", ErrRejected)
+	}
+	form := url.Values{"secret": {v.secret}, "response": {response}, "remoteip": {remoteIP}}
+	resp, err := v.client.Post(ctx, v.url, "application/x-www-form-urlencoded", strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("captcha provider answered %s", resp.Status)
+	}
+	var result verifyResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("decoding the captcha provider answer: %w", err)
+	}
+	if !result.Success {
+		return fmt.Errorf("%w: %s", ErrRejected, strings.Join(result.ErrorCodes, ", "))
+	}
+	return nil
+}
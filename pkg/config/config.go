@@ -0,0 +1,991 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nyaruka/phonenumbers"
+)
+
+const (
+	defaultDatabaseURL = "host=localhost user=postgres password=postgres dbname=testdb port=5432 sslmode=disable"
+	defaultSecret      = "insecure-development-secret"
+
+	// minSecretLength is the shortest SECRET accepted outside development
+	minSecretLength = 32
+)
+
+// Deployment environments. Production hard-fails on insecure settings that
+// development and staging only warn about.
+const (
+	EnvDevelopment = "dev"
+	EnvStaging     = "staging"
+	EnvProduction  = "prod"
+)
+
+// Config holds the service configuration
+type Config struct {
+	Environment     string
+	Port            string
+	Listen          Listen
+	DatabaseURL     string
+	DatabaseLog     DatabaseLogConfig
+	DatabaseMonitor DatabaseMonitorConfig
+	// DatabaseTimeout bounds the statements run for a single request
+	DatabaseTimeout time.Duration
+	DebugMode       bool
+	// Secrets sign links and tokens. The first signs new ones and every entry
+	// verifies, so a new secret can be put first while the old one lets the
+	// links already sent out keep working until it is dropped.
+	Secrets         []string
+	AdminToken      string
+	RequireVerified bool
+	ShutdownTimeout time.Duration
+	Upgrade         UpgradeConfig
+	AllowedOrigins  []Origin
+	RateLimit       RateLimitConfig
+	LoadShed        LoadShedConfig
+	RequestTimeout  RequestTimeoutConfig
+	BotGuard        BotGuardConfig
+	TLS             TLSConfig
+	Schedules       ScheduleConfig
+	Outbox          OutboxConfig
+	Events          EventsConfig
+	Mail            MailConfig
+	Exports         ExportConfig
+	Storage         StorageConfig
+	// DeletedUserRetention is how long soft-deleted users are kept before they are purged
+	DeletedUserRetention time.Duration
+	Health               HealthConfig
+	Debug                DebugConfig
+	Phone                PhoneConfig
+	// UserMetadata registers the metadata keys users may carry, mapped to the
+	// type of their values
+	UserMetadata map[string]string
+	LogRedact    LogRedactConfig
+	LogSink      LogSinkConfig
+	S3           S3Config
+	Alerts       AlertConfig
+	// TrustedProxies are the networks of the reverse proxies whose
+	// X-Forwarded-For header names the client; other peers are the client
+	TrustedProxies []*net.IPNet
+	// PublicURL is the base URL clients reach the service at, which the links
+	// sent by email and returned by the API are built on
+	PublicURL string
+}
+
+// Listen is the address the server accepts connections on
+type Listen struct {
+	// Network is "tcp" or "unix"
+	Network string
+	// Address is a host:port for tcp or a socket path for unix
+	Address string
+	// SocketMode is the file mode applied to a unix socket
+	SocketMode os.FileMode
+}
+
+// String returns the listen address as a URL
+func (l Listen) String() string {
+	return l.Network + "://" + l.Address
+}
+
+// parseListen parses tcp://host:port or unix:///path/to/socket
+func parseListen(value string) (Listen, error) {
+	u, err := url.Parse(value)
+	if err != nil {
+		return Listen{}, fmt.Errorf("invalid LISTEN %q: %w", value, err)
+	}
+	switch u.Scheme {
+	case "tcp":
+		if u.Host == "" {
+			return Listen{}, fmt.Errorf("LISTEN %q is missing host:port", value)
+		}
+		return Listen{Network: "tcp", Address: u.Host}, nil
+	case "unix":
+		if u.Path == "" {
+			return Listen{}, fmt.Errorf("LISTEN %q is missing the socket path", value)
+		}
+		return Listen{Network: "unix", Address: u.Path}, nil
+	default:
+		return Listen{}, fmt.Errorf("LISTEN %q must use tcp:// or unix://", value)
+	}
+}
+
+// TLSConfig controls HTTPS termination by the server itself
+type TLSConfig struct {
+	// CertFile and KeyFile serve a static certificate
+	CertFile string
+	KeyFile  string
+	// AutocertDomains obtains certificates from Let's Encrypt for these hosts only
+	AutocertDomains []string
+	// AutocertCacheDir stores obtained certificates across restarts
+	AutocertCacheDir string
+	// AutocertEmail is the ACME account contact address
+	AutocertEmail string
+	// HSTSMaxAge is announced in the Strict-Transport-Security header
+	HSTSMaxAge time.Duration
+}
+
+// Enabled reports whether the server terminates TLS itself
+func (t TLSConfig) Enabled() bool {
+	return t.CertFile != "" || len(t.AutocertDomains) > 0
+}
+
+// Autocert reports whether certificates are obtained through ACME
+func (t TLSConfig) Autocert() bool {
+	return len(t.AutocertDomains) > 0
+}
+
+// Database log levels, from quietest to most verbose
+const (
+	DBLogSilent = "silent"
+	DBLogError  = "error"
+	DBLogWarn   = "warn"
+	DBLogInfo   = "info"
+)
+
+// DatabaseLogConfig controls the logging of SQL statements
+type DatabaseLogConfig struct {
+	// Level is silent, error, warn (errors and slow queries) or info (every statement)
+	Level string
+	// SlowThreshold is the duration above which a statement is logged as slow
+	SlowThreshold time.Duration
+	// Params logs statement parameters instead of their placeholders
+	Params bool
+}
+
+// DatabaseMonitorConfig controls the pings that detect a lost database and
+// recover its connection pool
+type DatabaseMonitorConfig struct {
+	// PingInterval is how often the database is pinged while it is reachable
+	PingInterval time.Duration
+	// Failures is the number of consecutive failed pings after which the
+	// database is considered lost and its idle connections are discarded
+	Failures int
+}
+
+// Rate limit stores
+const (
+	RateLimitStoreMemory = "memory"
+	RateLimitStoreRedis  = "redis"
+)
+
+// RateLimitConfig limits the requests each client may make per window
+type RateLimitConfig struct {
+	// Requests is the number of requests allowed per window; 0 disables the limit
+	Requests int
+	Window   time.Duration
+	// Store is memory, counting per instance, or redis, counting across instances
+	Store string
+	// RedisURL locates the Redis server of the redis store
+	RedisURL string
+	// ExemptNets are client networks, such as internal services, that are
+	// never limited
+	ExemptNets []*net.IPNet
+	// ExemptUserAgents are parts of the User-Agent of health checkers that
+	// are never limited
+	ExemptUserAgents []string
+	// WarnPercent is the share of the limit after which responses carry a
+	// warning; 0 disables the warning
+	WarnPercent int
+}
+
+// Captcha providers
+const (
+	CaptchaHCaptcha  = "hcaptcha"
+	CaptchaTurnstile = "turnstile"
+)
+
+// BotGuardConfig guards the public forms, such as signing up, against
+// automated submissions
+type BotGuardConfig struct {
+	// HoneypotField is a body field the form hides from people; submissions
+	// filling it are rejected. Empty disables the check.
+	HoneypotField string
+	// MinSubmitTime is how long a form must be open before it is submitted;
+	// 0 disables the check
+	MinSubmitTime time.Duration
+	// CaptchaProvider is hcaptcha or turnstile; empty disables captchas
+	CaptchaProvider string
+	CaptchaSecret   string
+}
+
+// LoadShedConfig bounds the requests handled at once, so an overload is
+// answered with 503 instead of exhausting the database connection pool
+type LoadShedConfig struct {
+	// MaxInFlight is the number of requests handled concurrently; 0 disables shedding
+	MaxInFlight int
+	// MaxQueue requests beyond MaxInFlight wait up to QueueTimeout for a slot
+	MaxQueue     int
+	QueueTimeout time.Duration
+}
+
+// RequestTimeoutConfig bounds how long a request may take, so its database
+// statements and outbound calls are cancelled once the client stopped waiting
+type RequestTimeoutConfig struct {
+	// Default applies to every route without an override; 0 disables it
+	Default time.Duration
+	// Routes overrides Default for routes given as "METHOD /path", with the
+	// path as registered, such as "GET /users/:id"; 0 leaves a route unbounded
+	Routes map[string]time.Duration
+}
+
+// UpgradeConfig controls zero-downtime restarts, where a new process inherits
+// the listener while the old one drains
+type UpgradeConfig struct {
+	// Enabled starts a new process of the current binary on SIGUSR2
+	Enabled bool
+	// PIDFile receives the PID of the process serving once it is ready
+	PIDFile string
+	// Timeout is how long the new process has to become ready
+	Timeout time.Duration
+}
+
+// DebugConfig controls the profiling endpoints
+type DebugConfig struct {
+	// PprofEnabled mounts net/http/pprof under /debug/pprof
+	PprofEnabled bool
+	// PprofAllowedNets may reach the profiling endpoints without the admin token
+	PprofAllowedNets []*net.IPNet
+}
+
+// ScheduleConfig holds the cron schedule of every recurring task. A schedule
+// of "off" disables the task.
+type ScheduleConfig struct {
+	// InvitationCleanup deletes expired, unaccepted invitations
+	InvitationCleanup string
+	// ExportCleanup deletes export files past their retention
+	ExportCleanup string
+	// UserPurge permanently deletes users soft-deleted longer than the retention period
+	UserPurge string
+	// LeaderElection runs the tasks only on the replica holding a database
+	// lock, so they run once across replicas
+	LeaderElection bool
+}
+
+// Event publishers the outbox relay can deliver to
+const (
+	PublisherLog     = "log"
+	PublisherWebhook = "webhook"
+	PublisherKafka   = "kafka"
+	PublisherNATS    = "nats"
+)
+
+// OutboxConfig controls the relay publishing outbox events
+type OutboxConfig struct {
+	// Interval is how often the relay polls for unpublished events
+	Interval time.Duration
+	// BatchSize is the maximum number of events published per poll
+	BatchSize int
+}
+
+// EventsConfig selects and configures where domain events are published
+type EventsConfig struct {
+	// Publisher is one of log, webhook, kafka or nats
+	Publisher string
+	// WebhookURL receives every event as a JSON POST
+	WebhookURL string
+	// KafkaBrokers and KafkaTopic receive events keyed by aggregate
+	KafkaBrokers []string
+	KafkaTopic   string
+	// NATSURL and NATSSubjectPrefix receive events on "<prefix>.<event type>"
+	NATSURL           string
+	NATSSubjectPrefix string
+}
+
+// Mail delivery modes
+const (
+	MailModeLog  = "log"
+	MailModeSMTP = "smtp"
+)
+
+// MailConfig controls how emails are delivered
+type MailConfig struct {
+	// Mode is smtp to send emails or log to only write them to the log
+	Mode string
+	// From is the sender address
+	From string
+	// SMTPHost and SMTPPort address the relay; SMTPUsername enables authentication
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	// QueueSize is the number of emails buffered for asynchronous sending
+	QueueSize int
+	// CheckMX looks up the mail servers of new user email domains in the background
+	CheckMX bool
+}
+
+// ExportConfig controls background exports
+type ExportConfig struct {
+	// Retention is how long export files are kept
+	Retention time.Duration
+	// LinkTTL is how long a download link stays valid
+	LinkTTL time.Duration
+	// QueueSize is the number of exports that may wait to run
+	QueueSize int
+}
+
+// PhoneConfig controls how user phone numbers are parsed
+type PhoneConfig struct {
+	// DefaultRegion parses numbers given without an international prefix
+	DefaultRegion string
+	// AllowedRegions restricts accepted numbers to these regions; empty allows all
+	AllowedRegions []string
+}
+
+// Types of the values of metadata keys
+const (
+	MetadataString = "string"
+	MetadataNumber = "number"
+	MetadataBool   = "bool"
+)
+
+// Storage backends
+const (
+	StorageLocal = "local"
+	StorageS3    = "s3"
+	StorageGCS   = "gcs"
+)
+
+// StorageConfig selects where files such as exports are stored
+type StorageConfig struct {
+	// Backend is local, s3 or gcs
+	Backend string
+	// Dir stores the files of the local backend
+	Dir string
+	// Bucket stores the files of the s3 and gcs backends
+	Bucket string
+}
+
+// S3Config locates an S3-compatible object store
+type S3Config struct {
+	Endpoint string
+	Region   string
+	// AccessKeyID and SecretAccessKey are read from the AWS environment
+	// variables, shared credentials file or instance role when unset
+	AccessKeyID     string
+	SecretAccessKey string
+	// TLS connects to the endpoint over HTTPS
+	TLS bool
+}
+
+// Fields masked in log output
+const (
+	LogFieldEmail   = "email"
+	LogFieldPhone   = "phone"
+	LogFieldToken   = "token"
+	LogFieldSession = "session"
+)
+
+// LogFields lists every field that can be masked in log output
+var LogFields = []string{LogFieldEmail, LogFieldPhone, LogFieldToken, LogFieldSession}
+
+// LogRedactConfig controls the masking of personal data and secrets in log output
+type LogRedactConfig struct {
+	// Fields are the masked fields; none disables masking
+	Fields []string
+	// Strict masks every field entirely, without hints such as an email's domain
+	Strict bool
+}
+
+// Log sinks that log entries can be shipped to
+const (
+	LogSinkNone          = "none"
+	LogSinkLoki          = "loki"
+	LogSinkElasticsearch = "elasticsearch"
+)
+
+// LogSinkConfig controls shipping log entries to a log store, alongside stdout
+// and stderr
+type LogSinkConfig struct {
+	// Type is none, loki or elasticsearch
+	Type string
+	// URL is the base URL of the Loki or Elasticsearch server; credentials
+	// in it are sent as basic authentication
+	URL string
+	// Index receives the entries shipped to Elasticsearch
+	Index string
+	// BatchSize is the most entries sent in one request and FlushInterval the
+	// longest an entry waits for a batch to fill
+	BatchSize     int
+	FlushInterval time.Duration
+	// BufferSize is the number of entries held while the sink is slow or
+	// unreachable; further entries are dropped
+	BufferSize int
+}
+
+// Alert webhook formats
+const (
+	AlertFormatSlack = "slack"
+	AlertFormatTeams = "teams"
+)
+
+// AlertConfig controls the operational alerts posted to a chat webhook
+type AlertConfig struct {
+	// WebhookURL is a Slack or Teams incoming webhook; alerts are disabled when unset
+	WebhookURL string
+	// Format is slack or teams
+	Format string
+	// Interval is the minimum time between two alerts of the same kind
+	Interval time.Duration
+	// ErrorThreshold server errors within ErrorWindow raise an alert; 0 disables it
+	ErrorThreshold int
+	ErrorWindow    time.Duration
+}
+
+// HealthConfig holds the thresholds and timeouts used by the health checks
+type HealthConfig struct {
+	// MemoryWarnMB is the heap size above which the service reports degraded
+	MemoryWarnMB uint64
+	// MemoryCriticalMB is the heap size above which the service reports down
+	MemoryCriticalMB uint64
+	// CheckTimeout bounds every check that does not set its own timeout
+	CheckTimeout time.Duration
+	// DatabaseTimeout bounds the database ping
+	DatabaseTimeout time.Duration
+}
+
+// Load reads the configuration. Command-line flags take precedence over the
+// environment; when CONFIG_FILE names a YAML file of the same keys, its values
+// are used for every setting given neither as a flag nor in the environment.
+func Load(flags Flags) (*Config, error) {
+	src, err := newSource(flags)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &Config{
+		Environment: src.getEnv("ENVIRONMENT", EnvDevelopment),
+		Port:        src.getEnv("PORT", "8080"),
+		DatabaseURL: src.getEnv("DATABASE_URL", defaultDatabaseURL),
+		DatabaseLog: DatabaseLogConfig{
+			Level:         src.getEnv("DB_LOG_LEVEL", DBLogWarn),
+			SlowThreshold: src.getEnvDuration("DB_SLOW_QUERY_THRESHOLD", 200*time.Millisecond),
+			Params:        src.getEnvBool("DB_LOG_PARAMS", false),
+		},
+		DatabaseMonitor: DatabaseMonitorConfig{
+			PingInterval: src.getEnvDuration("DB_PING_INTERVAL", 10*time.Second),
+			Failures:     int(src.getEnvUint("DB_PING_FAILURES", 3)),
+		},
+		DatabaseTimeout: src.getEnvDuration("DB_REQUEST_TIMEOUT", 10*time.Second),
+		DebugMode:       src.getEnvBool("DEBUG", false),
+		Secrets:         src.getEnvSlice("SECRET"),
+		AdminToken:      src.getEnv("ADMIN_TOKEN", ""),
+		RequireVerified: src.getEnvBool("REQUIRE_VERIFIED", false),
+		ShutdownTimeout: src.getEnvDuration("SHUTDOWN_TIMEOUT", 30*time.Second),
+		Upgrade: UpgradeConfig{
+			Enabled: src.getEnvBool("UPGRADE_ENABLED", false),
+			PIDFile: src.getEnv("UPGRADE_PID_FILE", ""),
+			Timeout: src.getEnvDuration("UPGRADE_TIMEOUT", time.Minute),
+		},
+		RateLimit: RateLimitConfig{
+			Requests:         int(src.getEnvUint("RATE_LIMIT_REQUESTS", 0)),
+			Window:           src.getEnvDuration("RATE_LIMIT_WINDOW", time.Minute),
+			Store:            src.getEnv("RATE_LIMIT_STORE", RateLimitStoreMemory),
+			RedisURL:         src.getEnv("RATE_LIMIT_REDIS_URL", ""),
+			ExemptUserAgents: src.getEnvSlice("RATE_LIMIT_EXEMPT_AGENTS"),
+			WarnPercent:      int(src.getEnvUint("RATE_LIMIT_WARN_PERCENT", 80)),
+		},
+		Schedules: ScheduleConfig{
+			InvitationCleanup: src.getEnv("SCHEDULE_INVITATION_CLEANUP", "@hourly"),
+			ExportCleanup:     src.getEnv("SCHEDULE_EXPORT_CLEANUP", "@hourly"),
+			UserPurge:         src.getEnv("SCHEDULE_USER_PURGE", "@daily"),
+			LeaderElection:    src.getEnvBool("SCHEDULER_LEADER_ELECTION", true),
+		},
+		LoadShed: LoadShedConfig{
+			MaxInFlight:  int(src.getEnvUint("LOAD_SHED_MAX_IN_FLIGHT", 0)),
+			MaxQueue:     int(src.getEnvUint("LOAD_SHED_MAX_QUEUE", 100)),
+			QueueTimeout: src.getEnvDuration("LOAD_SHED_QUEUE_TIMEOUT", 500*time.Millisecond),
+		},
+		BotGuard: BotGuardConfig{
+			HoneypotField:   src.getEnv("BOT_HONEYPOT_FIELD", ""),
+			MinSubmitTime:   src.getEnvDuration("BOT_MIN_SUBMIT_TIME", 0),
+			CaptchaProvider: src.getEnv("CAPTCHA_PROVIDER", ""),
+			CaptchaSecret:   src.getEnv("CAPTCHA_SECRET", ""),
+		},
+		RequestTimeout: RequestTimeoutConfig{
+			Default: src.getEnvDuration("REQUEST_TIMEOUT", 30*time.Second),
+			Routes:  map[string]time.Duration{},
+		},
+		Outbox: OutboxConfig{
+			Interval:  src.getEnvDuration("OUTBOX_INTERVAL", 5*time.Second),
+			BatchSize: int(src.getEnvUint("OUTBOX_BATCH_SIZE", 100)),
+		},
+		Events: EventsConfig{
+			WebhookURL:        src.getEnv("OUTBOX_WEBHOOK_URL", ""),
+			KafkaBrokers:      src.getEnvSlice("KAFKA_BROKERS"),
+			KafkaTopic:        src.getEnv("KAFKA_TOPIC", "crud.events"),
+			NATSURL:           src.getEnv("NATS_URL", ""),
+			NATSSubjectPrefix: src.getEnv("NATS_SUBJECT_PREFIX", "crud"),
+		},
+		Mail: MailConfig{
+			Mode:         src.getEnv("MAIL_MODE", MailModeLog),
+			From:         src.getEnv("MAIL_FROM", ""),
+			SMTPHost:     src.getEnv("SMTP_HOST", ""),
+			SMTPPort:     int(src.getEnvUint("SMTP_PORT", 587)),
+			SMTPUsername: src.getEnv("SMTP_USERNAME", ""),
+			SMTPPassword: src.getEnv("SMTP_PASSWORD", ""),
+			QueueSize:    int(src.getEnvUint("MAIL_QUEUE_SIZE", 100)),
+			CheckMX:      src.getEnvBool("EMAIL_MX_CHECK", false),
+		},
+		DeletedUserRetention: src.getEnvDuration("DELETED_USER_RETENTION", 30*24*time.Hour),
+		Exports: ExportConfig{
+			Retention: src.getEnvDuration("EXPORT_RETENTION", 24*time.Hour),
+			LinkTTL:   src.getEnvDuration("EXPORT_LINK_TTL", time.Hour),
+			QueueSize: int(src.getEnvUint("EXPORT_QUEUE_SIZE", 10)),
+		},
+		Health: HealthConfig{
+			MemoryWarnMB:     src.getEnvUint("HEALTH_MEMORY_WARN_MB", 512),
+			MemoryCriticalMB: src.getEnvUint("HEALTH_MEMORY_CRITICAL_MB", 1024),
+			CheckTimeout:     src.getEnvDuration("HEALTH_CHECK_TIMEOUT", 2*time.Second),
+			DatabaseTimeout:  src.getEnvDuration("HEALTH_DB_TIMEOUT", time.Second),
+		},
+		TLS: TLSConfig{
+			CertFile:         src.getEnv("TLS_CERT", ""),
+			KeyFile:          src.getEnv("TLS_KEY", ""),
+			AutocertDomains:  src.getEnvSlice("TLS_AUTOCERT_DOMAINS"),
+			AutocertCacheDir: src.getEnv("TLS_AUTOCERT_CACHE_DIR", "autocert-cache"),
+			AutocertEmail:    src.getEnv("TLS_AUTOCERT_EMAIL", ""),
+			HSTSMaxAge:       src.getEnvDuration("HSTS_MAX_AGE", 365*24*time.Hour),
+		},
+		Debug: DebugConfig{
+			PprofEnabled: src.getEnvBool("PPROF_ENABLED", false),
+		},
+		Storage: StorageConfig{
+			Backend: src.getEnv("STORAGE_BACKEND", StorageLocal),
+			// EXPORT_DIR is the former name of STORAGE_DIR
+			Dir:    src.getEnv("STORAGE_DIR", src.getEnv("EXPORT_DIR", filepath.Join(os.TempDir(), "crud-exports"))),
+			Bucket: src.getEnv("STORAGE_BUCKET", ""),
+		},
+		S3: S3Config{
+			Endpoint:        src.getEnv("S3_ENDPOINT", "s3.amazonaws.com"),
+			Region:          src.getEnv("S3_REGION", ""),
+			AccessKeyID:     src.getEnv("S3_ACCESS_KEY_ID", ""),
+			SecretAccessKey: src.getEnv("S3_SECRET_ACCESS_KEY", ""),
+			TLS:             src.getEnvBool("S3_TLS", true),
+		},
+		LogSink: LogSinkConfig{
+			Type:          src.getEnv("LOG_SINK", LogSinkNone),
+			URL:           src.getEnv("LOG_SINK_URL", ""),
+			Index:         src.getEnv("LOG_SINK_INDEX", "crud-logs"),
+			BatchSize:     int(src.getEnvUint("LOG_SINK_BATCH_SIZE", 500)),
+			FlushInterval: src.getEnvDuration("LOG_SINK_FLUSH_INTERVAL", 2*time.Second),
+			BufferSize:    int(src.getEnvUint("LOG_SINK_BUFFER_SIZE", 10000)),
+		},
+		Alerts: AlertConfig{
+			WebhookURL:     src.getEnv("ALERT_WEBHOOK_URL", ""),
+			Format:         src.getEnv("ALERT_FORMAT", AlertFormatSlack),
+			Interval:       src.getEnvDuration("ALERT_INTERVAL", 5*time.Minute),
+			ErrorThreshold: int(src.getEnvUint("ALERT_ERROR_THRESHOLD", 20)),
+			ErrorWindow:    src.getEnvDuration("ALERT_ERROR_WINDOW", time.Minute),
+		},
+		Phone: PhoneConfig{
+			DefaultRegion:  strings.ToUpper(src.getEnv("PHONE_DEFAULT_REGION", "US")),
+			AllowedRegions: src.getEnvSlice("PHONE_ALLOWED_REGIONS"),
+		},
+	}
+	for i, region := range cfg.Phone.AllowedRegions {
+		cfg.Phone.AllowedRegions[i] = strings.ToUpper(region)
+	}
+	// Without an explicit publisher, a configured webhook is used and events are logged otherwise
+	defaultPublisher := PublisherLog
+	if cfg.Events.WebhookURL != "" {
+		defaultPublisher = PublisherWebhook
+	}
+	cfg.Events.Publisher = src.getEnv("EVENTS_PUBLISHER", defaultPublisher)
+
+	if len(cfg.Secrets) == 0 {
+		cfg.Secrets = []string{defaultSecret}
+	}
+
+	// Every field is masked unless configured otherwise, and entirely in production
+	cfg.LogRedact.Fields = src.getEnvSlice("LOG_REDACT")
+	if len(cfg.LogRedact.Fields) == 0 {
+		cfg.LogRedact.Fields = LogFields
+	} else if len(cfg.LogRedact.Fields) == 1 && cfg.LogRedact.Fields[0] == "none" {
+		cfg.LogRedact.Fields = nil
+	}
+	cfg.LogRedact.Strict = src.getEnvBool("LOG_REDACT_STRICT", cfg.Strict())
+	cfg.PublicURL = strings.TrimSuffix(src.getEnv("PUBLIC_URL", defaultPublicURL(cfg.Port)), "/")
+
+	// LISTEN takes precedence over PORT, which only selects a TCP port
+	cfg.Listen = Listen{Network: "tcp", Address: ":" + cfg.Port}
+	if value := src.getEnv("LISTEN", ""); value != "" {
+		if cfg.Listen, err = parseListen(value); err != nil {
+			return nil, err
+		}
+	}
+	mode, err := strconv.ParseUint(src.getEnv("LISTEN_SOCKET_MODE", "0660"), 8, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid LISTEN_SOCKET_MODE: %w", err)
+	}
+	cfg.Listen.SocketMode = os.FileMode(mode)
+
+	for _, value := range src.getEnvSlice("ALLOWED_ORIGINS") {
+		origin, err := ParseOrigin(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ALLOWED_ORIGINS entry: %w", err)
+		}
+		cfg.AllowedOrigins = append(cfg.AllowedOrigins, origin)
+	}
+	cfg.UserMetadata = map[string]string{}
+	for _, entry := range src.getEnvSlice("USER_METADATA_SCHEMA") {
+		key, kind, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid USER_METADATA_SCHEMA entry %q, expected key:type", entry)
+		}
+		cfg.UserMetadata[strings.TrimSpace(key)] = strings.TrimSpace(kind)
+	}
+	for _, entry := range src.getEnvSlice("REQUEST_TIMEOUT_ROUTES") {
+		route, value, ok := strings.Cut(entry, "=")
+		method, path, _ := strings.Cut(strings.TrimSpace(route), " ")
+		timeout, err := time.ParseDuration(strings.TrimSpace(value))
+		if !ok || method == "" || !strings.HasPrefix(path, "/") || err != nil {
+			return nil, fmt.Errorf("invalid REQUEST_TIMEOUT_ROUTES entry %q, expected \"METHOD /path=duration\"", entry)
+		}
+		cfg.RequestTimeout.Routes[strings.ToUpper(method)+" "+path] = timeout
+	}
+	for _, cidr := range src.getEnvSlice("TRUSTED_PROXIES") {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TRUSTED_PROXIES entry %q: %w", cidr, err)
+		}
+		cfg.TrustedProxies = append(cfg.TrustedProxies, n)
+	}
+	for _, cidr := range src.getEnvSlice("RATE_LIMIT_EXEMPT_CIDRS") {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RATE_LIMIT_EXEMPT_CIDRS entry %q: %w", cidr, err)
+		}
+		cfg.RateLimit.ExemptNets = append(cfg.RateLimit.ExemptNets, n)
+	}
+	for _, cidr := range src.getEnvSlice("PPROF_ALLOWED_CIDRS") {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid PPROF_ALLOWED_CIDRS entry %q: %w", cidr, err)
+		}
+		cfg.Debug.PprofAllowedNets = append(cfg.Debug.PprofAllowedNets, n)
+	}
+	// Values that do not parse fall back to their defaults, which only
+	// development and staging accept
+	if cfg.Strict() && len(src.invalid) > 0 {
+		return nil, fmt.Errorf("invalid configuration for %s: %w", cfg.Environment, errors.Join(src.invalid...))
+	}
+	for _, err := range src.invalid {
+		log.Printf("%v, using the default\n", err)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// defaultPublicURL is the PUBLIC_URL of a development server listening on port
+func defaultPublicURL(port string) string {
+	return "http://localhost:" + port
+}
+
+// Strict reports whether insecure settings are fatal rather than warnings
+func (c *Config) Strict() bool {
+	return c.Environment == EnvProduction
+}
+
+// Insecure lists the settings unfit for production, such as a weak SECRET
+func (c *Config) Insecure() []error {
+	var insecure []error
+	for i, secret := range c.Secrets {
+		switch {
+		case secret == defaultSecret:
+			insecure = append(insecure, errors.New("SECRET is not set, using an insecure development secret"))
+		case len(secret) < minSecretLength:
+			insecure = append(insecure, fmt.Errorf("SECRET entry %d is shorter than %d characters", i+1, minSecretLength))
+		}
+	}
+	if c.DatabaseURL == defaultDatabaseURL {
+		insecure = append(insecure, errors.New("DATABASE_URL is not set, using the default local database"))
+	}
+	if c.PublicURL == defaultPublicURL(c.Port) {
+		insecure = append(insecure, fmt.Errorf("PUBLIC_URL is not set, links sent out point to %s", c.PublicURL))
+	}
+	if c.DebugMode && c.Environment != EnvDevelopment {
+		insecure = append(insecure, fmt.Errorf("DEBUG must not be enabled in %s", c.Environment))
+	}
+	return insecure
+}
+
+// validate rejects inconsistent settings. Insecure settings are rejected in
+// strict mode and logged as warnings otherwise.
+func (c *Config) validate() error {
+	switch c.Environment {
+	case EnvDevelopment, EnvStaging, EnvProduction:
+	default:
+		return fmt.Errorf("ENVIRONMENT must be one of %s, %s or %s, got %q",
+			EnvDevelopment, EnvStaging, EnvProduction, c.Environment)
+	}
+
+	insecure := c.Insecure()
+	if c.Strict() && len(insecure) > 0 {
+		return fmt.Errorf("insecure configuration for %s: %w", c.Environment, errors.Join(insecure...))
+	}
+	for _, err := range insecure {
+		log.Println(err)
+	}
+	if c.Mail.Mode == MailModeLog && c.Environment != EnvDevelopment {
+		log.Printf("MAIL_MODE=%s only logs emails in %s\n", MailModeLog, c.Environment)
+	}
+
+	if u, err := url.Parse(c.PublicURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.RawQuery != "" || u.Fragment != "" {
+		return fmt.Errorf("PUBLIC_URL must be an http or https URL without query, got %q", c.PublicURL)
+	}
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		return errors.New("TLS_CERT and TLS_KEY must be set together")
+	}
+	if c.TLS.CertFile != "" && c.TLS.Autocert() {
+		return errors.New("TLS_CERT/TLS_KEY and TLS_AUTOCERT_DOMAINS are mutually exclusive")
+	}
+	if c.Health.MemoryWarnMB > c.Health.MemoryCriticalMB {
+		return fmt.Errorf("HEALTH_MEMORY_WARN_MB (%d) must not exceed HEALTH_MEMORY_CRITICAL_MB (%d)",
+			c.Health.MemoryWarnMB, c.Health.MemoryCriticalMB)
+	}
+	if c.Outbox.Interval <= 0 || c.Outbox.BatchSize <= 0 {
+		return errors.New("OUTBOX_INTERVAL and OUTBOX_BATCH_SIZE must be positive")
+	}
+	switch c.Events.Publisher {
+	case PublisherLog:
+	case PublisherWebhook:
+		if c.Events.WebhookURL == "" {
+			return errors.New("EVENTS_PUBLISHER=webhook requires OUTBOX_WEBHOOK_URL")
+		}
+	case PublisherKafka:
+		if len(c.Events.KafkaBrokers) == 0 {
+			return errors.New("EVENTS_PUBLISHER=kafka requires KAFKA_BROKERS")
+		}
+	case PublisherNATS:
+		if c.Events.NATSURL == "" {
+			return errors.New("EVENTS_PUBLISHER=nats requires NATS_URL")
+		}
+	default:
+		return fmt.Errorf("EVENTS_PUBLISHER must be one of %s, %s, %s or %s, got %q",
+			PublisherLog, PublisherWebhook, PublisherKafka, PublisherNATS, c.Events.Publisher)
+	}
+	switch c.BotGuard.CaptchaProvider {
+	case "":
+	case CaptchaHCaptcha, CaptchaTurnstile:
+		if c.BotGuard.CaptchaSecret == "" {
+			return fmt.Errorf("CAPTCHA_PROVIDER=%s requires CAPTCHA_SECRET", c.BotGuard.CaptchaProvider)
+		}
+	default:
+		return fmt.Errorf("CAPTCHA_PROVIDER must be %s or %s, got %q",
+			CaptchaHCaptcha, CaptchaTurnstile, c.BotGuard.CaptchaProvider)
+	}
+	switch c.Mail.Mode {
+	case MailModeLog:
+	case MailModeSMTP:
+		if c.Mail.SMTPHost == "" || c.Mail.From == "" {
+			return errors.New("MAIL_MODE=smtp requires SMTP_HOST and MAIL_FROM")
+		}
+	default:
+		return fmt.Errorf("MAIL_MODE must be %s or %s, got %q", MailModeLog, MailModeSMTP, c.Mail.Mode)
+	}
+	if c.Mail.QueueSize <= 0 {
+		return errors.New("MAIL_QUEUE_SIZE must be positive")
+	}
+	if c.Exports.Retention <= 0 || c.Exports.LinkTTL <= 0 || c.Exports.QueueSize <= 0 {
+		return errors.New("EXPORT_RETENTION, EXPORT_LINK_TTL and EXPORT_QUEUE_SIZE must be positive")
+	}
+	if c.DeletedUserRetention <= 0 {
+		return errors.New("DELETED_USER_RETENTION must be positive")
+	}
+	switch c.DatabaseLog.Level {
+	case DBLogSilent, DBLogError, DBLogWarn, DBLogInfo:
+	default:
+		return fmt.Errorf("DB_LOG_LEVEL must be one of %s, %s, %s or %s, got %q",
+			DBLogSilent, DBLogError, DBLogWarn, DBLogInfo, c.DatabaseLog.Level)
+	}
+	if c.DatabaseLog.SlowThreshold <= 0 {
+		return errors.New("DB_SLOW_QUERY_THRESHOLD must be positive")
+	}
+	if c.DatabaseMonitor.PingInterval <= 0 || c.DatabaseMonitor.Failures <= 0 {
+		return errors.New("DB_PING_INTERVAL and DB_PING_FAILURES must be positive")
+	}
+	if c.DatabaseTimeout <= 0 {
+		return errors.New("DB_REQUEST_TIMEOUT must be positive")
+	}
+	if c.DatabaseLog.Params && c.Strict() {
+		log.Println("DB_LOG_PARAMS writes query parameters, including personal data, to the log")
+	}
+	if c.RateLimit.Window <= 0 {
+		return errors.New("RATE_LIMIT_WINDOW must be positive")
+	}
+	if c.RateLimit.WarnPercent > 100 {
+		return fmt.Errorf("RATE_LIMIT_WARN_PERCENT must not exceed 100, got %d", c.RateLimit.WarnPercent)
+	}
+	switch c.RateLimit.Store {
+	case RateLimitStoreMemory:
+	case RateLimitStoreRedis:
+		if c.RateLimit.RedisURL == "" {
+			return errors.New("RATE_LIMIT_STORE=redis requires RATE_LIMIT_REDIS_URL")
+		}
+	default:
+		return fmt.Errorf("RATE_LIMIT_STORE must be %s or %s, got %q",
+			RateLimitStoreMemory, RateLimitStoreRedis, c.RateLimit.Store)
+	}
+	if c.LoadShed.QueueTimeout <= 0 {
+		return errors.New("LOAD_SHED_QUEUE_TIMEOUT must be positive")
+	}
+	if c.RequestTimeout.Default < 0 {
+		return errors.New("REQUEST_TIMEOUT must not be negative")
+	}
+	for route, timeout := range c.RequestTimeout.Routes {
+		if timeout < 0 {
+			return fmt.Errorf("REQUEST_TIMEOUT_ROUTES timeout of %s must not be negative", route)
+		}
+	}
+	if c.ShutdownTimeout <= 0 {
+		return errors.New("SHUTDOWN_TIMEOUT must be positive")
+	}
+	if c.Upgrade.Timeout <= 0 {
+		return errors.New("UPGRADE_TIMEOUT must be positive")
+	}
+	for _, field := range c.LogRedact.Fields {
+		if !slices.Contains(LogFields, field) {
+			return fmt.Errorf("LOG_REDACT must list fields of %s, got %q", strings.Join(LogFields, ", "), field)
+		}
+	}
+	for key, kind := range c.UserMetadata {
+		if !validMetadataKey(key) {
+			return fmt.Errorf("USER_METADATA_SCHEMA keys must be lowercase letters, digits and _ starting with a letter, got %q", key)
+		}
+		if kind != MetadataString && kind != MetadataNumber && kind != MetadataBool {
+			return fmt.Errorf("USER_METADATA_SCHEMA type of %s must be one of %s, %s or %s, got %q",
+				key, MetadataString, MetadataNumber, MetadataBool, kind)
+		}
+	}
+	switch c.Storage.Backend {
+	case StorageLocal:
+	case StorageS3, StorageGCS:
+		if c.Storage.Bucket == "" {
+			return fmt.Errorf("STORAGE_BACKEND=%s requires STORAGE_BUCKET", c.Storage.Backend)
+		}
+	default:
+		return fmt.Errorf("STORAGE_BACKEND must be one of %s, %s or %s, got %q",
+			StorageLocal, StorageS3, StorageGCS, c.Storage.Backend)
+	}
+	if (c.S3.AccessKeyID == "") != (c.S3.SecretAccessKey == "") {
+		return errors.New("S3_ACCESS_KEY_ID and S3_SECRET_ACCESS_KEY must be set together")
+	}
+	switch c.LogSink.Type {
+	case LogSinkNone:
+	case LogSinkLoki, LogSinkElasticsearch:
+		if c.LogSink.URL == "" {
+			return fmt.Errorf("LOG_SINK=%s requires LOG_SINK_URL", c.LogSink.Type)
+		}
+	default:
+		return fmt.Errorf("LOG_SINK must be one of %s, %s or %s, got %q",
+			LogSinkNone, LogSinkLoki, LogSinkElasticsearch, c.LogSink.Type)
+	}
+	if c.LogSink.BatchSize <= 0 || c.LogSink.FlushInterval <= 0 || c.LogSink.BufferSize <= 0 {
+		return errors.New("LOG_SINK_BATCH_SIZE, LOG_SINK_FLUSH_INTERVAL and LOG_SINK_BUFFER_SIZE must be positive")
+	}
+	switch c.Alerts.Format {
+	case AlertFormatSlack, AlertFormatTeams:
+	default:
+		return fmt.Errorf("ALERT_FORMAT must be %s or %s, got %q", AlertFormatSlack, AlertFormatTeams, c.Alerts.Format)
+	}
+	if c.Alerts.Interval <= 0 || c.Alerts.ErrorWindow <= 0 {
+		return errors.New("ALERT_INTERVAL and ALERT_ERROR_WINDOW must be positive")
+	}
+	supported := phonenumbers.GetSupportedRegions()
+	if _, ok := supported[c.Phone.DefaultRegion]; !ok {
+		return fmt.Errorf("PHONE_DEFAULT_REGION %q is not a supported region", c.Phone.DefaultRegion)
+	}
+	for _, region := range c.Phone.AllowedRegions {
+		if _, ok := supported[region]; !ok {
+			return fmt.Errorf("PHONE_ALLOWED_REGIONS entry %q is not a supported region", region)
+		}
+	}
+	if c.Health.CheckTimeout <= 0 || c.Health.DatabaseTimeout <= 0 {
+		return errors.New("health check timeouts must be positive")
+	}
+	return nil
+}
+
+func (s *source) getEnv(key, fallback string) string {
+	if value := s.lookup(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// getEnvSlice splits a comma-separated variable into its trimmed, non-empty entries
+func (s *source) getEnvSlice(key string) []string {
+	var values []string
+	for _, value := range strings.Split(s.lookup(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func (s *source) getEnvBool(key string, fallback bool) bool {
+	value := s.lookup(key)
+	if value == "" {
+		return fallback
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		s.invalid = append(s.invalid, fmt.Errorf("invalid %s %q: %w", key, value, err))
+		return fallback
+	}
+	return parsed
+}
+
+func (s *source) getEnvUint(key string, fallback uint64) uint64 {
+	value := s.lookup(key)
+	if value == "" {
+		return fallback
+	}
+	parsed, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		s.invalid = append(s.invalid, fmt.Errorf("invalid %s %q: %w", key, value, err))
+		return fallback
+	}
+	return parsed
+}
+
+func (s *source) getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value := s.lookup(key)
+	if value == "" {
+		return fallback
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		s.invalid = append(s.invalid, fmt.Errorf("invalid %s %q: %w", key, value, err))
+		return fallback
+	}
+	return parsed
+}
+
+// validMetadataKey reports whether key can name a metadata key, which must
+// also be usable as the name of a query parameter and a JSON key
+func validMetadataKey(key string) bool {
+	if key == "" || key[0] < 'a' || key[0] > 'z' {
+		return false
+	}
+	for _, r := range key {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '_' {
+			return false
+		}
+	}
+	return true
+}
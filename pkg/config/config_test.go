@@ -0,0 +1,30 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadInvalidValues(t *testing.T) {
+	t.Setenv("SHUTDOWN_TIMEOUT", "30")
+	t.Setenv("DB_PING_FAILURES", "-1")
+	t.Setenv("REQUIRE_VERIFIED", "maybe")
+
+	// Development falls back to the defaults
+	cfg, err := Load(Flags{})
+	require.NoError(t, err)
+	assert.Equal(t, 30*time.Second, cfg.ShutdownTimeout)
+	assert.Equal(t, 3, cfg.DatabaseMonitor.Failures)
+	assert.False(t, cfg.RequireVerified)
+
+	// Production reports every invalid value
+	t.Setenv("ENVIRONMENT", EnvProduction)
+	_, err = Load(Flags{})
+	require.Error(t, err)
+	for _, key := range []string{"SHUTDOWN_TIMEOUT", "DB_PING_FAILURES", "REQUIRE_VERIFIED"} {
+		assert.Contains(t, err.Error(), "invalid "+key)
+	}
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"strconv"
+
+	"github.com/spf13/pflag"
+)
+
+// Flags holds the settings explicitly given on the command line, keyed by the
+// name of the environment variable they override
+type Flags map[string]string
+
+// RegisterFlags adds the configuration flags to fs. The returned function
+// collects the flags actually passed once fs is parsed, so unset flags never
+// mask the environment or config file.
+func RegisterFlags(fs *pflag.FlagSet) func() Flags {
+	port := fs.String("port", "", "HTTP listen port (PORT)")
+	databaseURL := fs.String("database-url", "", "PostgreSQL connection string (DATABASE_URL)")
+	configFile := fs.String("config", "", "path to a YAML config file (CONFIG_FILE)")
+	debug := fs.Bool("debug", false, "run in debug mode (DEBUG)")
+
+	return func() Flags {
+		flags := Flags{}
+		// Cobra parses persistent flags through the command's own flag set, so
+		// look for changed flags rather than the ones fs itself parsed
+		fs.VisitAll(func(f *pflag.Flag) {
+			if !f.Changed {
+				return
+			}
+			switch f.Name {
+			case "port":
+				flags["PORT"] = *port
+			case "database-url":
+				flags["DATABASE_URL"] = *databaseURL
+			case "config":
+				flags["CONFIG_FILE"] = *configFile
+			case "debug":
+				flags["DEBUG"] = strconv.FormatBool(*debug)
+			}
+		})
+		return flags
+	}
+}
@@ -0,0 +1,66 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Origin is an allowed CORS origin. A host starting with "*." matches any
+// subdomain of the remainder, and the origin "*" matches every origin.
+type Origin struct {
+	Scheme string
+	Host   string
+}
+
+// Any reports whether o matches every origin
+func (o Origin) Any() bool {
+	return o.Scheme == "" && o.Host == "*"
+}
+
+// String returns the origin as configured
+func (o Origin) String() string {
+	if o.Any() {
+		return "*"
+	}
+	return o.Scheme + "://" + o.Host
+}
+
+// Matches reports whether the Origin request header value origin is allowed by o
+func (o Origin) Matches(origin string) bool {
+	if o.Any() {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil || !strings.EqualFold(u.Scheme, o.Scheme) {
+		return false
+	}
+	host := strings.ToLower(u.Host)
+	if suffix, ok := strings.CutPrefix(o.Host, "*"); ok {
+		return strings.HasSuffix(host, suffix) && len(host) > len(suffix)
+	}
+	return host == o.Host
+}
+
+// ParseOrigin validates an allowed origin of the form scheme://host[:port],
+// where host may start with "*." to allow any subdomain, or the value "*"
+func ParseOrigin(value string) (Origin, error) {
+	if value == "*" {
+		return Origin{Host: "*"}, nil
+	}
+	u, err := url.Parse(value)
+	if err != nil {
+		return Origin{}, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return Origin{}, fmt.Errorf("origin %q must use http or https", value)
+	}
+	if u.Host == "" || u.User != nil || (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" {
+		return Origin{}, fmt.Errorf("origin %q must be of the form scheme://host[:port]", value)
+	}
+	host := strings.ToLower(u.Host)
+	if strings.Contains(strings.TrimPrefix(host, "*."), "*") {
+		return Origin{}, fmt.Errorf("origin %q may only use a wildcard as its first label", value)
+	}
+	return Origin{Scheme: u.Scheme, Host: host}, nil
+}
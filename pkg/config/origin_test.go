@@ -0,0 +1,70 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOrigin(t *testing.T) {
+	tests := []struct {
+		value string
+		want  Origin
+		err   bool
+	}{
+		{value: "*", want: Origin{Host: "*"}},
+		{value: "https://app.example.com", want: Origin{Scheme: "https", Host: "app.example.com"}},
+		{value: "http://localhost:3000/", want: Origin{Scheme: "http", Host: "localhost:3000"}},
+		{value: "https://App.Example.com", want: Origin{Scheme: "https", Host: "app.example.com"}},
+		{value: "https://*.example.com", want: Origin{Scheme: "https", Host: "*.example.com"}},
+		{value: "ftp://example.com", err: true},
+		{value: "example.com", err: true},
+		{value: "https://", err: true},
+		{value: "https://example.com/app", err: true},
+		{value: "https://example.com?x=1", err: true},
+		{value: "https://user@example.com", err: true},
+		{value: "https://app.*.example.com", err: true},
+		{value: "https://**.example.com", err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseOrigin(tt.value)
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.want.String(), got.String())
+		})
+	}
+}
+
+func TestOriginMatches(t *testing.T) {
+	tests := []struct {
+		allowed string
+		origin  string
+		want    bool
+	}{
+		{allowed: "*", origin: "https://anything.example", want: true},
+		{allowed: "https://app.example.com", origin: "https://app.example.com", want: true},
+		{allowed: "https://app.example.com", origin: "HTTPS://APP.EXAMPLE.COM", want: true},
+		{allowed: "https://app.example.com", origin: "http://app.example.com", want: false},
+		{allowed: "https://app.example.com", origin: "https://app.example.com:8443", want: false},
+		{allowed: "https://app.example.com", origin: "https://evil.app.example.com", want: false},
+		{allowed: "https://*.example.com", origin: "https://app.example.com", want: true},
+		{allowed: "https://*.example.com", origin: "https://a.b.example.com", want: true},
+		{allowed: "https://*.example.com", origin: "https://example.com", want: false},
+		{allowed: "https://*.example.com", origin: "https://.example.com", want: false},
+		{allowed: "https://*.example.com", origin: "https://evilexample.com", want: false},
+		{allowed: "https://app.example.com", origin: "null", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.allowed+" "+tt.origin, func(t *testing.T) {
+			o, err := ParseOrigin(tt.allowed)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, o.Matches(tt.origin))
+		})
+	}
+}
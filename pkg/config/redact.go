@@ -0,0 +1,189 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"time"
+)
+
+const redacted = "REDACTED"
+
+// dsnPassword matches the password of a keyword/value connection string
+var dsnPassword = regexp.MustCompile(`(password\s*=\s*)('(?:[^'\\]|\\.)*'|\S+)`)
+
+// Redacted returns the effective configuration keyed by setting name, with
+// secrets masked so it can be shown to operators
+func (c *Config) Redacted() map[string]any {
+	origins := make([]string, 0, len(c.AllowedOrigins))
+	for _, o := range c.AllowedOrigins {
+		origins = append(origins, o.String())
+	}
+	return map[string]any{
+		"ENVIRONMENT":                 c.Environment,
+		"PORT":                        c.Port,
+		"LISTEN":                      c.Listen.String(),
+		"LISTEN_SOCKET_MODE":          fmt.Sprintf("%04o", c.Listen.SocketMode),
+		"DATABASE_URL":                RedactDSN(c.DatabaseURL),
+		"DB_LOG_LEVEL":                c.DatabaseLog.Level,
+		"DB_SLOW_QUERY_THRESHOLD":     c.DatabaseLog.SlowThreshold.String(),
+		"DB_LOG_PARAMS":               c.DatabaseLog.Params,
+		"DB_PING_INTERVAL":            c.DatabaseMonitor.PingInterval.String(),
+		"DB_PING_FAILURES":            c.DatabaseMonitor.Failures,
+		"DB_REQUEST_TIMEOUT":          c.DatabaseTimeout.String(),
+		"DEBUG":                       c.DebugMode,
+		"SECRET":                      masks(c.Secrets),
+		"ADMIN_TOKEN":                 mask(c.AdminToken),
+		"REQUIRE_VERIFIED":            c.RequireVerified,
+		"SHUTDOWN_TIMEOUT":            c.ShutdownTimeout.String(),
+		"UPGRADE_ENABLED":             c.Upgrade.Enabled,
+		"UPGRADE_PID_FILE":            c.Upgrade.PIDFile,
+		"UPGRADE_TIMEOUT":             c.Upgrade.Timeout.String(),
+		"ALLOWED_ORIGINS":             origins,
+		"TRUSTED_PROXIES":             netStrings(c.TrustedProxies),
+		"PUBLIC_URL":                  c.PublicURL,
+		"RATE_LIMIT_REQUESTS":         c.RateLimit.Requests,
+		"RATE_LIMIT_WINDOW":           c.RateLimit.Window.String(),
+		"RATE_LIMIT_STORE":            c.RateLimit.Store,
+		"RATE_LIMIT_REDIS_URL":        redactURL(c.RateLimit.RedisURL),
+		"RATE_LIMIT_EXEMPT_CIDRS":     netStrings(c.RateLimit.ExemptNets),
+		"RATE_LIMIT_EXEMPT_AGENTS":    c.RateLimit.ExemptUserAgents,
+		"RATE_LIMIT_WARN_PERCENT":     c.RateLimit.WarnPercent,
+		"LOAD_SHED_MAX_IN_FLIGHT":     c.LoadShed.MaxInFlight,
+		"LOAD_SHED_MAX_QUEUE":         c.LoadShed.MaxQueue,
+		"LOAD_SHED_QUEUE_TIMEOUT":     c.LoadShed.QueueTimeout.String(),
+		"REQUEST_TIMEOUT":             c.RequestTimeout.Default.String(),
+		"REQUEST_TIMEOUT_ROUTES":      durationStrings(c.RequestTimeout.Routes),
+		"BOT_HONEYPOT_FIELD":          c.BotGuard.HoneypotField,
+		"BOT_MIN_SUBMIT_TIME":         c.BotGuard.MinSubmitTime.String(),
+		"CAPTCHA_PROVIDER":            c.BotGuard.CaptchaProvider,
+		"CAPTCHA_SECRET":              mask(c.BotGuard.CaptchaSecret),
+		"TLS_CERT":                    c.TLS.CertFile,
+		"TLS_KEY":                     c.TLS.KeyFile,
+		"TLS_AUTOCERT_DOMAINS":        c.TLS.AutocertDomains,
+		"TLS_AUTOCERT_CACHE_DIR":      c.TLS.AutocertCacheDir,
+		"TLS_AUTOCERT_EMAIL":          c.TLS.AutocertEmail,
+		"HSTS_MAX_AGE":                c.TLS.HSTSMaxAge.String(),
+		"SCHEDULE_INVITATION_CLEANUP": c.Schedules.InvitationCleanup,
+		"EVENTS_PUBLISHER":            c.Events.Publisher,
+		"OUTBOX_WEBHOOK_URL":          redactURL(c.Events.WebhookURL),
+		"KAFKA_BROKERS":               c.Events.KafkaBrokers,
+		"KAFKA_TOPIC":                 c.Events.KafkaTopic,
+		"NATS_URL":                    redactURL(c.Events.NATSURL),
+		"NATS_SUBJECT_PREFIX":         c.Events.NATSSubjectPrefix,
+		"OUTBOX_INTERVAL":             c.Outbox.Interval.String(),
+		"OUTBOX_BATCH_SIZE":           c.Outbox.BatchSize,
+		"MAIL_MODE":                   c.Mail.Mode,
+		"MAIL_FROM":                   c.Mail.From,
+		"SMTP_HOST":                   c.Mail.SMTPHost,
+		"SMTP_PORT":                   c.Mail.SMTPPort,
+		"SMTP_USERNAME":               c.Mail.SMTPUsername,
+		"SMTP_PASSWORD":               mask(c.Mail.SMTPPassword),
+		"MAIL_QUEUE_SIZE":             c.Mail.QueueSize,
+		"EMAIL_MX_CHECK":              c.Mail.CheckMX,
+		"SCHEDULE_EXPORT_CLEANUP":     c.Schedules.ExportCleanup,
+		"SCHEDULE_USER_PURGE":         c.Schedules.UserPurge,
+		"SCHEDULER_LEADER_ELECTION":   c.Schedules.LeaderElection,
+		"DELETED_USER_RETENTION":      c.DeletedUserRetention.String(),
+		"STORAGE_BACKEND":             c.Storage.Backend,
+		"STORAGE_DIR":                 c.Storage.Dir,
+		"STORAGE_BUCKET":              c.Storage.Bucket,
+		"EXPORT_RETENTION":            c.Exports.Retention.String(),
+		"EXPORT_LINK_TTL":             c.Exports.LinkTTL.String(),
+		"EXPORT_QUEUE_SIZE":           c.Exports.QueueSize,
+		"HEALTH_MEMORY_WARN_MB":       c.Health.MemoryWarnMB,
+		"HEALTH_MEMORY_CRITICAL_MB":   c.Health.MemoryCriticalMB,
+		"HEALTH_CHECK_TIMEOUT":        c.Health.CheckTimeout.String(),
+		"HEALTH_DB_TIMEOUT":           c.Health.DatabaseTimeout.String(),
+		"PPROF_ENABLED":               c.Debug.PprofEnabled,
+		"PPROF_ALLOWED_CIDRS":         netStrings(c.Debug.PprofAllowedNets),
+		"PHONE_DEFAULT_REGION":        c.Phone.DefaultRegion,
+		"PHONE_ALLOWED_REGIONS":       c.Phone.AllowedRegions,
+		"USER_METADATA_SCHEMA":        c.UserMetadata,
+		"LOG_REDACT":                  c.LogRedact.Fields,
+		"LOG_REDACT_STRICT":           c.LogRedact.Strict,
+		"LOG_SINK":                    c.LogSink.Type,
+		"LOG_SINK_URL":                redactURL(c.LogSink.URL),
+		"LOG_SINK_INDEX":              c.LogSink.Index,
+		"LOG_SINK_BATCH_SIZE":         c.LogSink.BatchSize,
+		"LOG_SINK_FLUSH_INTERVAL":     c.LogSink.FlushInterval.String(),
+		"LOG_SINK_BUFFER_SIZE":        c.LogSink.BufferSize,
+		"S3_ENDPOINT":                 c.S3.Endpoint,
+		"S3_REGION":                   c.S3.Region,
+		"S3_ACCESS_KEY_ID":            c.S3.AccessKeyID,
+		"S3_SECRET_ACCESS_KEY":        mask(c.S3.SecretAccessKey),
+		"S3_TLS":                      c.S3.TLS,
+		"ALERT_WEBHOOK_URL":           mask(c.Alerts.WebhookURL),
+		"ALERT_FORMAT":                c.Alerts.Format,
+		"ALERT_INTERVAL":              c.Alerts.Interval.String(),
+		"ALERT_ERROR_THRESHOLD":       c.Alerts.ErrorThreshold,
+		"ALERT_ERROR_WINDOW":          c.Alerts.ErrorWindow.String(),
+	}
+}
+
+// RedactDSN masks the password of a URL or keyword/value database connection string
+func RedactDSN(dsn string) string {
+	if u, err := url.Parse(dsn); err == nil && u.Scheme != "" {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), redacted)
+		}
+		q := u.Query()
+		if q.Has("password") {
+			q.Set("password", redacted)
+			u.RawQuery = q.Encode()
+		}
+		return u.String()
+	}
+	return dsnPassword.ReplaceAllString(dsn, "${1}"+redacted)
+}
+
+// redactURL masks credentials and the query string of a URL, which commonly carry tokens
+func redactURL(value string) string {
+	u, err := url.Parse(value)
+	if err != nil {
+		return mask(value)
+	}
+	if u.User != nil {
+		u.User = url.User(redacted)
+	}
+	if u.RawQuery != "" {
+		u.RawQuery = redacted
+	}
+	return u.String()
+}
+
+// mask hides a secret value while still showing whether it is set
+func mask(value string) string {
+	if value == "" {
+		return ""
+	}
+	return redacted
+}
+
+// masks masks every value
+func masks(values []string) []string {
+	s := make([]string, 0, len(values))
+	for _, value := range values {
+		s = append(s, mask(value))
+	}
+	return s
+}
+
+// netStrings lists networks in CIDR notation
+func netStrings(nets []*net.IPNet) []string {
+	s := make([]string, 0, len(nets))
+	for _, n := range nets {
+		s = append(s, n.String())
+	}
+	return s
+}
+
+// durationStrings formats durations by key
+func durationStrings(durations map[string]time.Duration) map[string]string {
+	s := make(map[string]string, len(durations))
+	for key, d := range durations {
+		s[key] = d.String()
+	}
+	return s
+}
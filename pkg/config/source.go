@@ -0,0 +1,61 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// source resolves settings from command-line flags, then the environment,
+// then the values read from the optional config file
+type source struct {
+	flags Flags
+	file  map[string]string
+	// invalid collects the values that did not parse and fell back to their default
+	invalid []error
+}
+
+// newSource reads the flat YAML config file named by the CONFIG_FILE flag or
+// variable; without one the source only consults flags and the environment
+func newSource(flags Flags) (*source, error) {
+	src := &source{flags: flags, file: map[string]string{}}
+	path := src.lookup("CONFIG_FILE")
+	if path == "" {
+		return src, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+	var values map[string]any
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+	for key, value := range values {
+		switch v := value.(type) {
+		case nil:
+		case []any:
+			items := make([]string, 0, len(v))
+			for _, item := range v {
+				items = append(items, fmt.Sprint(item))
+			}
+			src.file[key] = strings.Join(items, ",")
+		default:
+			src.file[key] = fmt.Sprint(v)
+		}
+	}
+	return src, nil
+}
+
+// lookup returns the flag value of key, else its environment value, else its config file value
+func (s *source) lookup(key string) string {
+	if value, ok := s.flags[key]; ok {
+		return value
+	}
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return s.file[key]
+}
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// Subscriber is notified with the new configuration after a successful reload
+type Subscriber interface {
+	OnConfigReload(cfg *Config)
+}
+
+// SubscriberFunc adapts a function to the Subscriber interface
+type SubscriberFunc func(cfg *Config)
+
+// OnConfigReload calls f(cfg)
+func (f SubscriberFunc) OnConfigReload(cfg *Config) { f(cfg) }
+
+// Watcher holds the current configuration and reloads it on SIGHUP.
+// Only reload-safe settings are taken from a reload: the allowed origins, the
+// rate limits, REQUIRE_VERIFIED, the health settings and the SQL log level.
+// The others are bound at startup and keep their original values until the
+// process restarts.
+type Watcher struct {
+	mu          sync.RWMutex
+	flags       Flags
+	current     *Config
+	subscribers []Subscriber
+}
+
+// NewWatcher creates a Watcher starting from cfg, which was loaded with flags
+func NewWatcher(cfg *Config, flags Flags) *Watcher {
+	return &Watcher{current: cfg, flags: flags}
+}
+
+// Current returns the configuration in effect
+func (w *Watcher) Current() *Config {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.current
+}
+
+// Subscribe registers s to be notified after every successful reload
+func (w *Watcher) Subscribe(s Subscriber) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.subscribers = append(w.subscribers, s)
+}
+
+// Reload loads the configuration again and notifies subscribers. An invalid
+// configuration is rejected and the current one stays in effect.
+func (w *Watcher) Reload() error {
+	next, err := Load(w.flags)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	// Only the reload-safe settings are taken from the reload; every other
+	// setting, including any added later, keeps its startup value
+	prev := w.current
+	cfg := *prev
+	cfg.AllowedOrigins = next.AllowedOrigins
+	cfg.RateLimit = next.RateLimit
+	cfg.RateLimit.Store, cfg.RateLimit.RedisURL = prev.RateLimit.Store, prev.RateLimit.RedisURL
+	cfg.RequireVerified = next.RequireVerified
+	cfg.Health = next.Health
+	cfg.DatabaseLog.Level = next.DatabaseLog.Level
+	w.current = &cfg
+	subscribers := append([]Subscriber(nil), w.subscribers...)
+	w.mu.Unlock()
+
+	for _, s := range subscribers {
+		s.OnConfigReload(&cfg)
+	}
+	return nil
+}
+
+// Watch reloads the configuration on every SIGHUP until ctx is done
+func (w *Watcher) Watch(ctx context.Context) {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGHUP)
+	defer signal.Stop(sig)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sig:
+			if err := w.Reload(); err != nil {
+				log.Printf("config reload failed, keeping current configuration: %v\n", err)
+				continue
+			}
+			log.Println("Configuration reloaded")
+		}
+	}
+}
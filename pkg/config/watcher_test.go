@@ -0,0 +1,35 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatcherReloadTakesOnlyReloadSafeSettings(t *testing.T) {
+	t.Setenv("PORT", "8080")
+	t.Setenv("DB_LOG_LEVEL", DBLogWarn)
+	t.Setenv("DB_LOG_PARAMS", "false")
+	t.Setenv("REQUIRE_VERIFIED", "false")
+	cfg, err := Load(Flags{})
+	require.NoError(t, err)
+	w := NewWatcher(cfg, Flags{})
+	var notified *Config
+	w.Subscribe(SubscriberFunc(func(cfg *Config) { notified = cfg }))
+
+	t.Setenv("PORT", "9090")
+	t.Setenv("DB_LOG_LEVEL", DBLogInfo)
+	t.Setenv("DB_LOG_PARAMS", "true")
+	t.Setenv("REQUIRE_VERIFIED", "true")
+	require.NoError(t, w.Reload())
+
+	current := w.Current()
+	assert.Same(t, current, notified)
+	assert.True(t, current.RequireVerified)
+	assert.Equal(t, DBLogInfo, current.DatabaseLog.Level)
+	assert.Equal(t, "8080", current.Port)
+	assert.False(t, current.DatabaseLog.Params)
+	// The configuration handed out before the reload is left untouched
+	assert.False(t, cfg.RequireVerified)
+}
@@ -2,20 +2,17 @@ package database
 
 import (
 	"log"
-	"os"
+
+	"github.com/rkgcloud/crud/pkg/config"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
-// ConnectDB connects to the PostgresSQL database
-func ConnectDB() (*gorm.DB, error) {
-	dsn := os.Getenv("DATABASE_URL")
-	if dsn == "" {
-		dsn = "host=localhost user=postgres password=postgres dbname=testdb port=5432 sslmode=disable"
-	}
-	log.Printf("connection string %q\n", dsn)
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+// ConnectDB connects to the PostgresSQL database, logging SQL according to logCfg
+func ConnectDB(dsn string, logCfg config.DatabaseLogConfig) (*gorm.DB, error) {
+	log.Printf("connection string %q\n", config.RedactDSN(dsn))
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: NewLogger(logCfg)})
 	if err != nil {
 		log.Printf("failed to connect database: %v\n", err)
 		return nil, err
@@ -0,0 +1,136 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/rkgcloud/crud/pkg/config"
+	"github.com/rkgcloud/crud/pkg/requestid"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Logger writes GORM's SQL log through slog, tagged with the request and
+// trace IDs of the statement's context. Its level follows configuration
+// reloads once it is subscribed to the config.Watcher.
+type Logger struct {
+	level         *atomic.Int64
+	slowThreshold time.Duration
+	params        bool
+	log           *slog.Logger
+}
+
+// logLevels maps DB_LOG_LEVEL values to GORM log levels
+var logLevels = map[string]logger.LogLevel{
+	config.DBLogSilent: logger.Silent,
+	config.DBLogError:  logger.Error,
+	config.DBLogWarn:   logger.Warn,
+	config.DBLogInfo:   logger.Info,
+}
+
+// NewLogger creates a Logger writing to slog's default logger
+func NewLogger(cfg config.DatabaseLogConfig) *Logger {
+	level := &atomic.Int64{}
+	level.Store(int64(logLevels[cfg.Level]))
+	return &Logger{
+		level:         level,
+		slowThreshold: cfg.SlowThreshold,
+		params:        cfg.Params,
+		log:           slog.Default().With("component", "gorm"),
+	}
+}
+
+// LogMode returns a copy of the logger at level, which reloads leave alone
+func (l *Logger) LogMode(level logger.LogLevel) logger.Interface {
+	next := *l
+	next.level = &atomic.Int64{}
+	next.level.Store(int64(level))
+	return &next
+}
+
+// OnConfigReload implements config.Subscriber, applying DB_LOG_LEVEL
+func (l *Logger) OnConfigReload(cfg *config.Config) {
+	l.level.Store(int64(logLevels[cfg.DatabaseLog.Level]))
+}
+
+// logLevel is the level statements are currently logged at
+func (l *Logger) logLevel() logger.LogLevel {
+	return logger.LogLevel(l.level.Load())
+}
+
+// Info logs a GORM info message
+func (l *Logger) Info(ctx context.Context, msg string, data ...any) {
+	if l.logLevel() >= logger.Info {
+		l.log.InfoContext(ctx, fmt.Sprintf(msg, data...), contextAttrs(ctx)...)
+	}
+}
+
+// Warn logs a GORM warning
+func (l *Logger) Warn(ctx context.Context, msg string, data ...any) {
+	if l.logLevel() >= logger.Warn {
+		l.log.WarnContext(ctx, fmt.Sprintf(msg, data...), contextAttrs(ctx)...)
+	}
+}
+
+// Error logs a GORM error
+func (l *Logger) Error(ctx context.Context, msg string, data ...any) {
+	if l.logLevel() >= logger.Error {
+		l.log.ErrorContext(ctx, fmt.Sprintf(msg, data...), contextAttrs(ctx)...)
+	}
+}
+
+// Trace logs a statement when it failed, was slower than the threshold or
+// every statement is logged. Missing records are expected and not errors.
+func (l *Logger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	level := l.logLevel()
+	if level <= logger.Silent {
+		return
+	}
+	elapsed := time.Since(begin)
+	failed := err != nil && !errors.Is(err, gorm.ErrRecordNotFound)
+	slow := elapsed > l.slowThreshold
+	switch {
+	case failed && level >= logger.Error:
+	case slow && level >= logger.Warn:
+	case level >= logger.Info:
+	default:
+		return
+	}
+
+	sql, rows := fc()
+	attrs := append(contextAttrs(ctx), "sql", sql, "rows", rows, "duration", elapsed)
+	switch {
+	case failed:
+		l.log.ErrorContext(ctx, "query failed", append(attrs, "error", err)...)
+	case slow:
+		l.log.WarnContext(ctx, "slow query", append(attrs, "threshold", l.slowThreshold)...)
+	default:
+		l.log.InfoContext(ctx, "query", attrs...)
+	}
+}
+
+// ParamsFilter drops the statement parameters unless they are logged, so the
+// SQL keeps its placeholders instead of personal data
+func (l *Logger) ParamsFilter(_ context.Context, sql string, params ...any) (string, []any) {
+	if l.params {
+		return sql, params
+	}
+	return sql, nil
+}
+
+// contextAttrs returns the request and trace IDs of ctx as log attributes
+func contextAttrs(ctx context.Context) []any {
+	var attrs []any
+	if id := requestid.FromContext(ctx); id != "" {
+		attrs = append(attrs, "request_id", id)
+	}
+	if trace := requestid.TraceFromContext(ctx); trace != "" {
+		attrs = append(attrs, "trace_id", trace)
+	}
+	return attrs
+}
@@ -0,0 +1,148 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"expvar"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/rkgcloud/crud/pkg/config"
+
+	"gorm.io/gorm"
+)
+
+// Connection pool counters served at /debug/vars
+var (
+	dbUp           = expvar.NewInt("db_up")
+	dbPingFailures = expvar.NewInt("db_ping_failures_total")
+	dbPoolResets   = expvar.NewInt("db_pool_resets_total")
+)
+
+// monitored is the pool of the last Monitor created, whose usage is served at
+// /debug/vars as db_pool; in_use reaching max_open, or a growing wait_count,
+// means requests queue for connections
+var monitored atomic.Pointer[sql.DB]
+
+func init() {
+	expvar.Publish("db_pool", expvar.Func(func() any {
+		db := monitored.Load()
+		if db == nil {
+			return nil
+		}
+		stats := db.Stats()
+		return map[string]int64{
+			"max_open":      int64(stats.MaxOpenConnections),
+			"open":          int64(stats.OpenConnections),
+			"in_use":        int64(stats.InUse),
+			"idle":          int64(stats.Idle),
+			"wait_count":    stats.WaitCount,
+			"wait_ms_total": stats.WaitDuration.Milliseconds(),
+		}
+	}))
+}
+
+// Pool settings; maxIdleConns is the database/sql default, set explicitly so
+// the monitor can restore it
+const (
+	maxIdleConns    = 2
+	connMaxLifetime = 30 * time.Minute
+	// maxBackoff bounds the delay between pings while the database is lost
+	maxBackoff = time.Minute
+)
+
+// Monitor pings the database and recovers the connection pool after it was
+// lost, for instance to a failover. database/sql replaces connections that
+// fail, but idle connections to a server that vanished can hang rather than
+// fail. Once enough consecutive pings failed, the monitor discards the idle
+// connections and keeps every new statement on a fresh connection, pinging
+// with a growing delay until the database answers again.
+type Monitor struct {
+	db       *sql.DB
+	interval time.Duration
+	failures int
+	// onChange is called when the database is lost or reachable again
+	onChange func(up bool, err error)
+	done     chan struct{}
+}
+
+// NewMonitor creates a Monitor of the pool behind db. onChange, which may be
+// nil, is called with up false once the database is considered lost and with
+// up true once it answers again.
+func NewMonitor(db *gorm.DB, cfg config.DatabaseMonitorConfig, onChange func(up bool, err error)) (*Monitor, error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxIdleConns(maxIdleConns)
+	// Connections are renewed regularly so none outlives a failover for long
+	sqlDB.SetConnMaxLifetime(connMaxLifetime)
+	monitored.Store(sqlDB)
+	if onChange == nil {
+		onChange = func(bool, error) {}
+	}
+	return &Monitor{db: sqlDB, interval: cfg.PingInterval, failures: cfg.Failures, onChange: onChange, done: make(chan struct{})}, nil
+}
+
+// Run pings the database until ctx is done
+func (m *Monitor) Run(ctx context.Context) {
+	defer close(m.done)
+	dbUp.Set(1)
+	failed, lost := 0, false
+	delay := m.interval
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+
+		err := m.ping(ctx)
+		switch {
+		case err == nil && lost:
+			log.Println("database is reachable again, restoring the connection pool")
+			m.db.SetMaxIdleConns(maxIdleConns)
+			dbUp.Set(1)
+			m.onChange(true, nil)
+			failed, lost, delay = 0, false, m.interval
+		case err == nil:
+			failed = 0
+		case ctx.Err() != nil:
+			return
+		default:
+			dbPingFailures.Add(1)
+			failed++
+			if lost {
+				delay = min(delay*2, maxBackoff)
+				continue
+			}
+			if failed >= m.failures {
+				log.Printf("database lost after %d failed pings, resetting the connection pool: %v\n", failed, err)
+				// Closes the idle connections and keeps none until the database is back
+				m.db.SetMaxIdleConns(0)
+				dbPoolResets.Add(1)
+				dbUp.Set(0)
+				m.onChange(false, err)
+				lost, delay = true, time.Second
+			}
+		}
+	}
+}
+
+// Wait blocks until Run has returned or ctx is done
+func (m *Monitor) Wait(ctx context.Context) error {
+	select {
+	case <-m.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ping checks the database within the ping interval
+func (m *Monitor) ping(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, m.interval)
+	defer cancel()
+	return m.db.PingContext(ctx)
+}
@@ -0,0 +1,40 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Keys of the request session in the gin context
+const (
+	sessionKey = "db_session"
+	contextKey = "db_context"
+)
+
+// Session binds a session of db to every request, so handlers take their
+// database from the request and its statements are cancelled with it. The
+// statements of a request share a budget of timeout; once it is spent, they
+// fail with context.DeadlineExceeded.
+func Session(db *gorm.DB, timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Set(sessionKey, db.WithContext(ctx))
+		c.Set(contextKey, ctx)
+		c.Next()
+	}
+}
+
+// Get returns the database session of the request. It panics outside Session.
+func Get(c *gin.Context) *gorm.DB {
+	return c.MustGet(sessionKey).(*gorm.DB)
+}
+
+// Context returns the context of the database session of the request, for
+// repositories that bind the database themselves. It panics outside Session.
+func Context(c *gin.Context) context.Context {
+	return c.MustGet(contextKey).(context.Context)
+}
@@ -0,0 +1,80 @@
+package dberr
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// Kinds of database errors, matched with errors.Is on a translated error
+var (
+	// ErrNotFound is returned when a queried record does not exist
+	ErrNotFound = errors.New("record not found")
+	// ErrConflict is returned when a write violates a unique constraint
+	ErrConflict = errors.New("record already exists")
+	// ErrConstraint is returned when a write violates a foreign key, not-null
+	// or check constraint, or a value does not fit its column
+	ErrConstraint = errors.New("constraint violation")
+)
+
+// PostgreSQL error codes, see https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	codeStringTooLong       = "22001"
+	codeNumericOutOfRange   = "22003"
+	codeInvalidText         = "22P02"
+	codeNotNullViolation    = "23502"
+	codeForeignKeyViolation = "23503"
+	codeUniqueViolation     = "23505"
+	codeCheckViolation      = "23514"
+)
+
+// Error is a database error of a known kind
+type Error struct {
+	// Kind is ErrNotFound, ErrConflict or ErrConstraint
+	Kind error
+	// Constraint names the violated constraint, when the database reported one
+	Constraint string
+	Err        error
+}
+
+// Error describes the kind and constraint of the error
+func (e *Error) Error() string {
+	if e.Constraint != "" {
+		return fmt.Sprintf("%v (%s): %v", e.Kind, e.Constraint, e.Err)
+	}
+	return fmt.Sprintf("%v: %v", e.Kind, e.Err)
+}
+
+// Unwrap lets errors.Is match both the kind and the original error
+func (e *Error) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// Translate returns err as an *Error when it is a missing record or a
+// constraint violation, and err unchanged otherwise
+func Translate(err error) error {
+	if err == nil {
+		return nil
+	}
+	var translated *Error
+	if errors.As(err, &translated) {
+		return err
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &Error{Kind: ErrNotFound, Err: err}
+	}
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+	switch pgErr.Code {
+	case codeUniqueViolation:
+		return &Error{Kind: ErrConflict, Constraint: pgErr.ConstraintName, Err: err}
+	case codeForeignKeyViolation, codeNotNullViolation, codeCheckViolation,
+		codeStringTooLong, codeNumericOutOfRange, codeInvalidText:
+		return &Error{Kind: ErrConstraint, Constraint: pgErr.ConstraintName, Err: err}
+	}
+	return err
+}
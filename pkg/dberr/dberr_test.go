@@ -0,0 +1,53 @@
+package dberr
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestTranslate(t *testing.T) {
+	other := errors.New("connection refused")
+	tests := []struct {
+		name       string
+		err        error
+		kind       error
+		constraint string
+	}{
+		{name: "nil", err: nil},
+		{name: "other", err: other},
+		{name: "not found", err: gorm.ErrRecordNotFound, kind: ErrNotFound},
+		{name: "wrapped not found", err: fmt.Errorf("loading user: %w", gorm.ErrRecordNotFound), kind: ErrNotFound},
+		{name: "unique", err: &pgconn.PgError{Code: "23505", ConstraintName: "idx_users_email"}, kind: ErrConflict, constraint: "idx_users_email"},
+		{name: "foreign key", err: &pgconn.PgError{Code: "23503", ConstraintName: "fk_users_addresses"}, kind: ErrConstraint, constraint: "fk_users_addresses"},
+		{name: "not null", err: &pgconn.PgError{Code: "23502"}, kind: ErrConstraint},
+		{name: "check", err: &pgconn.PgError{Code: "23514", ConstraintName: "chk_users_age"}, kind: ErrConstraint, constraint: "chk_users_age"},
+		{name: "too long", err: &pgconn.PgError{Code: "22001"}, kind: ErrConstraint},
+		{name: "out of range", err: &pgconn.PgError{Code: "22003"}, kind: ErrConstraint},
+		{name: "invalid text", err: &pgconn.PgError{Code: "22P02"}, kind: ErrConstraint},
+		{name: "other postgres error", err: &pgconn.PgError{Code: "40001"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Translate(tt.err)
+			if tt.kind == nil {
+				assert.Equal(t, tt.err, got)
+				return
+			}
+			var translated *Error
+			if assert.ErrorAs(t, got, &translated) {
+				assert.Equal(t, tt.kind, translated.Kind)
+				assert.Equal(t, tt.constraint, translated.Constraint)
+			}
+			// Both the kind and the original error still match
+			assert.ErrorIs(t, got, tt.kind)
+			assert.ErrorIs(t, got, tt.err)
+			// Translating again leaves the error as it is
+			assert.Same(t, got, Translate(got))
+		})
+	}
+}
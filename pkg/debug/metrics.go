@@ -0,0 +1,37 @@
+package debug
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// metrics are the counters served at /debug/vars that /metrics exports, each
+// labelled by the keys of the maps it holds
+var metrics = map[string]*prometheus.Desc{
+	"http_responses_total":        prometheus.NewDesc("http_responses_total", "Responses by route and status class.", []string{"route", "class"}, nil),
+	"requests_in_flight":          prometheus.NewDesc("requests_in_flight", "Requests being handled past the load shedder.", nil, nil),
+	"requests_queued":             prometheus.NewDesc("requests_queued", "Requests waiting for a load shedder slot.", nil, nil),
+	"requests_shed_total":         prometheus.NewDesc("requests_shed_total", "Requests answered with 503 by the load shedder.", nil, nil),
+	"request_queue_wait_ms_total": prometheus.NewDesc("request_queue_wait_ms_total", "Time requests waited for a load shedder slot, in milliseconds.", nil, nil),
+	"rate_limited_total":          prometheus.NewDesc("rate_limited_total", "Requests answered with 429.", nil, nil),
+	"bot_rejections_total":        prometheus.NewDesc("bot_rejections_total", "Form submissions refused by the bot guard, by check.", []string{"check"}, nil),
+	"db_up":                       prometheus.NewDesc("db_up", "Whether the database answers pings.", nil, nil),
+	"db_ping_failures_total":      prometheus.NewDesc("db_ping_failures_total", "Database pings that failed.", nil, nil),
+	"db_pool_resets_total":        prometheus.NewDesc("db_pool_resets_total", "Connection pool resets after the database was lost.", nil, nil),
+	"db_pool":                     prometheus.NewDesc("db_pool", "Database connections and the waits for a free one, by statistic.", []string{"stat"}, nil),
+	"purged_users_total":          prometheus.NewDesc("purged_users_total", "Deleted users purged after the retention period.", nil, nil),
+}
+
+// RegisterMetrics mounts at /metrics on rg the counters of /debug/vars, with
+// the Go runtime and process metrics, in the Prometheus text format
+func RegisterMetrics(rg *gin.RouterGroup) {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewExpvarCollector(metrics),
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	rg.GET("/metrics", gin.WrapH(promhttp.HandlerFor(registry, promhttp.HandlerOpts{})))
+}
@@ -0,0 +1,25 @@
+package debug
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rejected := expvar.NewMap("bot_rejections_total")
+	rejected.Add("honeypot", 2)
+	r := gin.New()
+	RegisterMetrics(&r.RouterGroup)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `bot_rejections_total{check="honeypot"} 2`)
+	assert.Contains(t, w.Body.String(), "go_goroutines")
+}
@@ -0,0 +1,27 @@
+package debug
+
+import (
+	"expvar"
+	"net/http/pprof"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterVars mounts the expvar counters, such as purged_users_total, at /debug/vars on rg
+func RegisterVars(rg *gin.RouterGroup) {
+	rg.GET("/debug/vars", gin.WrapH(expvar.Handler()))
+}
+
+// RegisterPprof mounts the net/http/pprof handlers under /debug/pprof on rg
+func RegisterPprof(rg *gin.RouterGroup) {
+	g := rg.Group("/debug/pprof")
+	g.GET("/", gin.WrapF(pprof.Index))
+	g.GET("/cmdline", gin.WrapF(pprof.Cmdline))
+	g.GET("/profile", gin.WrapF(pprof.Profile))
+	g.POST("/symbol", gin.WrapF(pprof.Symbol))
+	g.GET("/symbol", gin.WrapF(pprof.Symbol))
+	g.GET("/trace", gin.WrapF(pprof.Trace))
+	for _, name := range []string{"allocs", "block", "goroutine", "heap", "mutex", "threadcreate"} {
+		g.GET("/"+name, gin.WrapH(pprof.Handler(name)))
+	}
+}
@@ -0,0 +1,91 @@
+package debug
+
+import (
+	"path"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Route describes a registered route and the handlers it runs
+type Route struct {
+	Method     string   `json:"method"`
+	Path       string   `json:"path"`
+	Middleware []string `json:"middleware"`
+	Handler    string   `json:"handler"`
+	// Timeout bounds the request context; it is "none" when the context is
+	// unbounded and empty when the route was not recorded
+	Timeout string `json:"timeout"`
+}
+
+// RouteTable lists the routes of an engine with what gin does not keep about
+// them: gin only exposes the last handler of a route, which is often an
+// adapter, so the middleware, handler and timeout of each route are recorded
+// as it is registered
+type RouteTable struct {
+	engine *gin.Engine
+	mu     sync.Mutex
+	routes map[string]Route
+}
+
+// NewRouteTable creates a RouteTable for the routes of engine
+func NewRouteTable(engine *gin.Engine) *RouteTable {
+	return &RouteTable{engine: engine, routes: map[string]Route{}}
+}
+
+// Record notes that the route of method and path runs middleware, then
+// handler, the function the last handler of the route runs or adapts, with
+// its context bounded by timeout, or unbounded when it is 0
+func (t *RouteTable) Record(method, path string, middleware []gin.HandlerFunc, handler any, timeout time.Duration) {
+	route := Route{Method: method, Path: path, Middleware: make([]string, 0, len(middleware)), Handler: shortName(funcName(handler)), Timeout: "none"}
+	for _, m := range middleware {
+		route.Middleware = append(route.Middleware, shortName(funcName(m)))
+	}
+	if timeout > 0 {
+		route.Timeout = timeout.String()
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.routes[method+" "+path] = route
+}
+
+// Routes lists the routes of the engine, sorted by path and method. Routes
+// registered without being recorded are listed with the last handler gin
+// knows and no middleware.
+func (t *RouteTable) Routes() []Route {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	infos := t.engine.Routes()
+	routes := make([]Route, 0, len(infos))
+	for _, info := range infos {
+		route, ok := t.routes[info.Method+" "+info.Path]
+		if !ok {
+			route = Route{Method: info.Method, Path: info.Path, Middleware: []string{}, Handler: shortName(info.Handler)}
+		}
+		routes = append(routes, route)
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	return routes
+}
+
+// funcName is the full name of the function fn
+func funcName(fn any) string {
+	return runtime.FuncForPC(reflect.ValueOf(fn).Pointer()).Name()
+}
+
+// shortName trims the import path from a function name, keeping its package,
+// the closure suffix of middleware returned by a constructor and the suffix
+// of method values
+func shortName(name string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(path.Base(name), ".func1"), "-fm")
+}
@@ -0,0 +1,34 @@
+package debug
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func auth(c *gin.Context) {}
+
+func listUsers(c *gin.Context) error { return nil }
+
+func TestRouteTableRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	table := NewRouteTable(r)
+	// The adapter gin keeps is not what the listing reports
+	adapt := func(c *gin.Context) {}
+	r.GET("/users", auth, adapt)
+	table.Record(http.MethodGet, "/users", []gin.HandlerFunc{auth}, listUsers, 5*time.Second)
+	r.GET("/health", adapt)
+	r.DELETE("/users", adapt)
+	table.Record(http.MethodDelete, "/users", nil, listUsers, 0)
+
+	routes := table.Routes()
+	assert.Equal(t, []Route{
+		{Method: http.MethodGet, Path: "/health", Middleware: []string{}, Handler: "debug.TestRouteTableRoutes"},
+		{Method: http.MethodDelete, Path: "/users", Middleware: []string{}, Handler: "debug.listUsers", Timeout: "none"},
+		{Method: http.MethodGet, Path: "/users", Middleware: []string{"debug.auth"}, Handler: "debug.listUsers", Timeout: "5s"},
+	}, routes)
+}
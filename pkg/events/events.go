@@ -0,0 +1,75 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/rkgcloud/crud/pkg/httpclient"
+	"github.com/rkgcloud/crud/pkg/models"
+)
+
+// Publisher delivers domain events to downstream consumers. Delivery is at
+// least once, so consumers should deduplicate on the event ID.
+type Publisher interface {
+	Publish(ctx context.Context, event models.OutboxEvent) error
+	Close() error
+}
+
+// LogPublisher writes events to the log; it is used when no destination is configured
+type LogPublisher struct{}
+
+// Publish logs event
+func (LogPublisher) Publish(ctx context.Context, event models.OutboxEvent) error {
+	log.Printf("event %d %s %s/%d\n", event.ID, event.Type, event.Aggregate, event.AggregateID)
+	return nil
+}
+
+// Close does nothing
+func (LogPublisher) Close() error { return nil }
+
+// WebhookPublisher POSTs each event as JSON to a URL
+type WebhookPublisher struct {
+	url    string
+	client *httpclient.Client
+}
+
+// NewWebhookPublisher creates a WebhookPublisher delivering to url. Transient
+// failures are retried briefly before the relay retries on its next poll.
+func NewWebhookPublisher(url string) *WebhookPublisher {
+	return &WebhookPublisher{url: url, client: httpclient.New(10*time.Second, 2)}
+}
+
+// Publish delivers event and fails unless the webhook answers with a 2xx status
+func (p *WebhookPublisher) Publish(ctx context.Context, event models.OutboxEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-ID", fmt.Sprint(event.ID))
+	req.Header.Set("X-Event-Type", event.Type)
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+	return nil
+}
+
+// Close releases idle connections
+func (p *WebhookPublisher) Close() error {
+	p.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,48 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/rkgcloud/crud/pkg/models"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// KafkaPublisher writes events to a Kafka topic. Messages are keyed by
+// aggregate so all events of one record land on the same partition in order.
+type KafkaPublisher struct {
+	writer *kafka.Writer
+}
+
+// NewKafkaPublisher creates a KafkaPublisher writing to topic on brokers
+func NewKafkaPublisher(brokers []string, topic string) *KafkaPublisher {
+	return &KafkaPublisher{writer: &kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+	}}
+}
+
+// Publish writes event and waits for all in-sync replicas to acknowledge it
+func (p *KafkaPublisher) Publish(ctx context.Context, event models.OutboxEvent) error {
+	value, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return p.writer.WriteMessages(ctx, kafka.Message{
+		Key:   []byte(fmt.Sprintf("%s/%d", event.Aggregate, event.AggregateID)),
+		Value: value,
+		Headers: []kafka.Header{
+			{Key: "event-id", Value: []byte(fmt.Sprint(event.ID))},
+			{Key: "event-type", Value: []byte(event.Type)},
+		},
+	})
+}
+
+// Close flushes pending messages and closes the connections
+func (p *KafkaPublisher) Close() error {
+	return p.writer.Close()
+}
@@ -0,0 +1,47 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/rkgcloud/crud/pkg/models"
+
+	"github.com/nats-io/nats.go"
+)
+
+// NATSPublisher publishes events on "<prefix>.<event type>" subjects
+type NATSPublisher struct {
+	conn   *nats.Conn
+	prefix string
+}
+
+// NewNATSPublisher connects to the NATS server at url
+func NewNATSPublisher(url, prefix string) (*NATSPublisher, error) {
+	conn, err := nats.Connect(url, nats.Name("crud"))
+	if err != nil {
+		return nil, fmt.Errorf("connecting to NATS: %w", err)
+	}
+	return &NATSPublisher{conn: conn, prefix: prefix}, nil
+}
+
+// Publish sends event and waits for the server to receive it
+func (p *NATSPublisher) Publish(ctx context.Context, event models.OutboxEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	msg := nats.NewMsg(p.prefix + "." + event.Type)
+	msg.Data = data
+	msg.Header.Set(nats.MsgIdHdr, fmt.Sprint(event.ID))
+	if err := p.conn.PublishMsg(msg); err != nil {
+		return err
+	}
+	// Publishing is asynchronous; the flush round-trip confirms the server has the message
+	return p.conn.FlushWithContext(ctx)
+}
+
+// Close drains pending messages and closes the connection
+func (p *NATSPublisher) Close() error {
+	return p.conn.Drain()
+}
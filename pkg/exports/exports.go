@@ -0,0 +1,243 @@
+package exports
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rkgcloud/crud/pkg/models"
+	"github.com/rkgcloud/crud/pkg/storage"
+
+	"gorm.io/gorm"
+)
+
+// FormatCSV is the only supported export format
+const FormatCSV = "csv"
+
+// ErrQueueFull is returned when a job cannot be queued without blocking
+var ErrQueueFull = errors.New("export queue is full")
+
+// batchSize is the number of users read per query while exporting
+const batchSize = 500
+
+// keyPrefix groups export files in storage
+const keyPrefix = "exports"
+
+// Exporter runs export jobs in the background and keeps the files in storage
+type Exporter struct {
+	db    *gorm.DB
+	store storage.Storage
+	queue chan uint
+	wg    sync.WaitGroup
+}
+
+// NewExporter creates an Exporter writing to store with a queue of queueSize jobs.
+// Jobs interrupted by a previous shutdown are marked as failed.
+func NewExporter(db *gorm.DB, store storage.Storage, queueSize int) (*Exporter, error) {
+	err := db.Model(&models.ExportJob{}).
+		Where("status IN ?", []string{models.ExportPending, models.ExportRunning}).
+		Updates(map[string]any{"status": models.ExportFailed, "error": "interrupted by shutdown"}).Error
+	if err != nil {
+		return nil, err
+	}
+	e := &Exporter{db: db, store: store, queue: make(chan uint, queueSize)}
+	e.wg.Add(1)
+	go e.work()
+	return e, nil
+}
+
+// Enqueue records a new job for format and queues it
+func (e *Exporter) Enqueue(format string) (*models.ExportJob, error) {
+	if format != FormatCSV {
+		return nil, fmt.Errorf("unsupported export format %q", format)
+	}
+	name, err := randomName()
+	if err != nil {
+		return nil, err
+	}
+	// FileName is the storage key of the file
+	key := storage.Key(keyPrefix, time.Now(), name+"."+format)
+	job := &models.ExportJob{Format: format, Status: models.ExportPending, FileName: key}
+	if err := e.db.Create(job).Error; err != nil {
+		return nil, err
+	}
+	select {
+	case e.queue <- job.ID:
+		return job, nil
+	default:
+		e.fail(job.ID, ErrQueueFull)
+		return nil, ErrQueueFull
+	}
+}
+
+// Open reads the file produced by job
+func (e *Exporter) Open(ctx context.Context, job models.ExportJob) (io.ReadCloser, error) {
+	return e.store.Open(ctx, job.FileName)
+}
+
+// SignedURL returns a link downloading the file of job directly from storage
+// for ttl, or "" when the storage cannot issue one and the file is served from Open
+func (e *Exporter) SignedURL(ctx context.Context, job models.ExportJob, ttl time.Duration) (string, error) {
+	return e.store.SignedURL(ctx, job.FileName, ttl, "users."+job.Format)
+}
+
+// Cleanup deletes jobs and files older than retention
+func (e *Exporter) Cleanup(ctx context.Context, retention time.Duration) error {
+	n, err := e.delete(ctx, e.db.Where("created_at < ?", time.Now().Add(-retention)))
+	if n > 0 {
+		log.Printf("deleted %d expired exports\n", n)
+	}
+	return err
+}
+
+// DeleteFinished deletes every completed or failed job and its file and
+// returns how many were deleted. Pending jobs are kept, as they only read
+// the users once they run.
+func (e *Exporter) DeleteFinished(ctx context.Context) (int, error) {
+	return e.delete(ctx, e.db.Where("status IN ?", []string{models.ExportCompleted, models.ExportFailed}))
+}
+
+// delete deletes the jobs matching query and their files
+func (e *Exporter) delete(ctx context.Context, query *gorm.DB) (int, error) {
+	var jobs []models.ExportJob
+	if err := query.WithContext(ctx).Find(&jobs).Error; err != nil {
+		return 0, err
+	}
+	for i, job := range jobs {
+		if err := e.store.Delete(ctx, job.FileName); err != nil {
+			return i, err
+		}
+		if err := e.db.WithContext(ctx).Unscoped().Delete(&job).Error; err != nil {
+			return i, err
+		}
+	}
+	return len(jobs), nil
+}
+
+// Close stops accepting jobs and waits for the running one to finish or ctx to be done
+func (e *Exporter) Close(ctx context.Context) error {
+	close(e.queue)
+	done := make(chan struct{})
+	go func() {
+		e.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// work runs queued jobs until the queue is closed
+func (e *Exporter) work() {
+	defer e.wg.Done()
+	for id := range e.queue {
+		if err := e.run(id); err != nil {
+			log.Printf("export %d failed: %v\n", id, err)
+			e.fail(id, err)
+		}
+	}
+}
+
+// run writes the export file for job id and marks the job completed
+func (e *Exporter) run(id uint) error {
+	var job models.ExportJob
+	if err := e.db.First(&job, id).Error; err != nil {
+		return err
+	}
+	if err := e.db.Model(&job).Update("status", models.ExportRunning).Error; err != nil {
+		return err
+	}
+
+	// The users are streamed to storage as they are read
+	r, w := io.Pipe()
+	var rows int
+	written := make(chan error, 1)
+	go func() {
+		var err error
+		rows, err = writeUsersCSV(e.db, w)
+		w.CloseWithError(err)
+		written <- err
+	}()
+	err := e.store.Put(context.Background(), job.FileName, r, "text/csv")
+	r.CloseWithError(err)
+	if err = errors.Join(<-written, err); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	return e.db.Model(&job).Updates(map[string]any{
+		"status":       models.ExportCompleted,
+		"rows":         rows,
+		"completed_at": &now,
+	}).Error
+}
+
+// fail marks job id as failed with err
+func (e *Exporter) fail(id uint, err error) {
+	if uerr := e.db.Model(&models.ExportJob{}).Where("id = ?", id).
+		Updates(map[string]any{"status": models.ExportFailed, "error": err.Error()}).Error; uerr != nil {
+		log.Printf("could not mark export %d failed: %v\n", id, uerr)
+	}
+}
+
+// writeUsersCSV streams all users to w in batches and returns the number of rows written
+func writeUsersCSV(db *gorm.DB, w io.Writer) (int, error) {
+	out := csv.NewWriter(w)
+	if err := out.Write([]string{"id", "name", "email", "age", "verified", "role", "created_at", "updated_at"}); err != nil {
+		return 0, err
+	}
+	rows := 0
+	var users []models.User
+	result := db.Order("id").FindInBatches(&users, batchSize, func(tx *gorm.DB, batch int) error {
+		for _, u := range users {
+			if err := out.Write([]string{
+				strconv.FormatUint(uint64(u.ID), 10),
+				csvSafe(u.Name),
+				csvSafe(u.Email),
+				strconv.Itoa(u.Age),
+				strconv.FormatBool(u.Verified),
+				u.Role,
+				u.CreatedAt.UTC().Format(time.RFC3339),
+				u.UpdatedAt.UTC().Format(time.RFC3339),
+			}); err != nil {
+				return err
+			}
+			rows++
+		}
+		return nil
+	})
+	if result.Error != nil {
+		return rows, result.Error
+	}
+	out.Flush()
+	return rows, out.Error()
+}
+
+// csvSafe stops spreadsheet applications from evaluating user-provided values as formulas
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsAny(value[:1], "=+-@\t\r") {
+		return "'" + value
+	}
+	return value
+}
+
+// randomName returns an unguessable file name
+func randomName() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,215 @@
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// Kind is the type of a filterable field, deciding how values are parsed and
+// which operators apply
+type Kind int
+
+const (
+	String Kind = iota
+	Int
+	Bool
+	Time
+)
+
+// Field maps a filter name to its column
+type Field struct {
+	Column string
+	Kind   Kind
+	// Lowercase fields are stored trimmed and lowercased, so their values
+	// are compared the same way
+	Lowercase bool
+}
+
+// Fields are the fields a listing may be filtered on, by filter name
+type Fields map[string]Field
+
+// operators are the supported comparisons and their SQL; ~ is a
+// case-insensitive substring match on strings
+var operators = map[string]string{
+	"=":  "=",
+	"!=": "<>",
+	">":  ">",
+	">=": ">=",
+	"<":  "<",
+	"<=": "<=",
+	"~":  "ILIKE",
+}
+
+// Condition is a single comparison of a filter expression
+type Condition struct {
+	Column string
+	Op     string
+	Value  any
+}
+
+// Error describes why a filter expression was rejected
+type Error struct {
+	Message string
+}
+
+func (e *Error) Error() string {
+	return "invalid filter: " + e.Message
+}
+
+func errorf(format string, args ...any) error {
+	return &Error{Message: fmt.Sprintf(format, args...)}
+}
+
+// Parse parses expressions like `age>=18 AND name~"ann"` into conditions on
+// fields. Only the listed fields and the operators =, !=, >, >=, <, <= and ~
+// are accepted, and values are parsed according to the field kind, so the
+// result is safe to apply to a query.
+func Parse(expr string, fields Fields) ([]Condition, error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+	var conds []Condition
+	for i := 0; i < len(tokens); {
+		if len(conds) > 0 {
+			if !strings.EqualFold(tokens[i].text, "AND") || tokens[i].quoted {
+				return nil, errorf("expected AND before %q", tokens[i].text)
+			}
+			i++
+		}
+		if i+3 > len(tokens) {
+			return nil, errorf("incomplete condition at the end of the expression")
+		}
+		cond, err := condition(tokens[i], tokens[i+1], tokens[i+2], fields)
+		if err != nil {
+			return nil, err
+		}
+		conds = append(conds, cond)
+		i += 3
+	}
+	return conds, nil
+}
+
+// Scope returns a GORM scope applying conds
+func Scope(conds []Condition) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		for _, c := range conds {
+			db = db.Where(c.Column+" "+c.Op+" ?", c.Value)
+		}
+		return db
+	}
+}
+
+// condition builds the comparison of a field, an operator and a value token
+func condition(name, op, value token, fields Fields) (Condition, error) {
+	field, ok := fields[name.text]
+	if !ok || name.quoted {
+		return Condition{}, errorf("unknown field %q", name.text)
+	}
+	sqlOp, ok := operators[op.text]
+	if !ok || op.quoted {
+		return Condition{}, errorf("unknown operator %q", op.text)
+	}
+	cond := Condition{Column: field.Column, Op: sqlOp}
+	if sqlOp == "ILIKE" {
+		if field.Kind != String {
+			return Condition{}, errorf("operator ~ only applies to text fields, not %q", name.text)
+		}
+		cond.Value = "%" + EscapeLike(value.text) + "%"
+		return cond, nil
+	}
+
+	var err error
+	switch field.Kind {
+	case String:
+		cond.Value = value.text
+		if field.Lowercase {
+			cond.Value = strings.ToLower(strings.TrimSpace(value.text))
+		}
+	case Int:
+		cond.Value, err = strconv.ParseInt(value.text, 10, 64)
+	case Bool:
+		if sqlOp != "=" && sqlOp != "<>" {
+			return Condition{}, errorf("operator %s does not apply to %q", op.text, name.text)
+		}
+		cond.Value, err = strconv.ParseBool(value.text)
+	case Time:
+		cond.Value, err = parseTime(value.text)
+	}
+	if err != nil {
+		return Condition{}, errorf("invalid value %q for %q", value.text, name.text)
+	}
+	return cond, nil
+}
+
+// parseTime accepts RFC 3339 timestamps and plain dates
+func parseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
+
+// EscapeLike escapes the LIKE wildcards of a literal
+func EscapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
+
+// reserved are the characters only accepted in quoted values
+const reserved = ";'(),"
+
+// token is a field name, operator, value or keyword of an expression
+type token struct {
+	text string
+	// quoted tokens are always values
+	quoted bool
+}
+
+// tokenize splits an expression into names, operators and values. Values
+// containing spaces, operator characters or any of ;'(), are written in double
+// quotes, with \" and \\ escapes; unquoted, those characters are rejected.
+func tokenize(expr string) ([]token, error) {
+	var tokens []token
+	runes := []rune(expr)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '"':
+			var b strings.Builder
+			i++
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				b.WriteRune(runes[i])
+			}
+			if i == len(runes) {
+				return nil, errorf("unterminated string")
+			}
+			i++
+			tokens = append(tokens, token{text: b.String(), quoted: true})
+		case strings.ContainsRune("=!<>~", r):
+			start := i
+			for i < len(runes) && strings.ContainsRune("=!<>~", runes[i]) {
+				i++
+			}
+			tokens = append(tokens, token{text: string(runes[start:i])})
+		case strings.ContainsRune(reserved, r):
+			return nil, errorf("unexpected %q, quote values containing it", r)
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && !strings.ContainsRune("=!<>~\""+reserved, runes[i]) {
+				i++
+			}
+			tokens = append(tokens, token{text: string(runes[start:i])})
+		}
+	}
+	return tokens, nil
+}
@@ -0,0 +1,132 @@
+package filter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+var testFields = Fields{
+	"name":       {Column: "name", Kind: String},
+	"email":      {Column: "email", Kind: String, Lowercase: true},
+	"age":        {Column: "age", Kind: Int},
+	"verified":   {Column: "verified", Kind: Bool},
+	"created_at": {Column: "created_at", Kind: Time},
+}
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		expr string
+		want []token
+	}{
+		{"", nil},
+		{"age>=18", []token{{text: "age"}, {text: ">="}, {text: "18"}}},
+		{"  age >= 18  ", []token{{text: "age"}, {text: ">="}, {text: "18"}}},
+		{`name~"ann lee"`, []token{{text: "name"}, {text: "~"}, {text: "ann lee", quoted: true}}},
+		{`name="say \"hi\" \\o/"`, []token{{text: "name"}, {text: "="}, {text: `say "hi" \o/`, quoted: true}}},
+		{`name="x;drop"`, []token{{text: "name"}, {text: "="}, {text: "x;drop", quoted: true}}},
+		{`name=""`, []token{{text: "name"}, {text: "="}, {text: "", quoted: true}}},
+		{"a=1 AND b!=2", []token{{text: "a"}, {text: "="}, {text: "1"}, {text: "AND"}, {text: "b"}, {text: "!="}, {text: "2"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := tokenize(tt.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestTokenizeRejects(t *testing.T) {
+	for _, expr := range []string{
+		`name="ann`,
+		`name=x;drop`,
+		`name=x'--`,
+		`name=lower(x)`,
+		`name=a,b`,
+		`name;drop table users=x`,
+	} {
+		t.Run(expr, func(t *testing.T) {
+			_, err := tokenize(expr)
+			var filterErr *Error
+			assert.ErrorAs(t, err, &filterErr)
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		expr string
+		want []Condition
+	}{
+		{"", nil},
+		{"age>=18", []Condition{{Column: "age", Op: ">=", Value: int64(18)}}},
+		{"verified!=true", []Condition{{Column: "verified", Op: "<>", Value: true}}},
+		{`name="Ann Lee"`, []Condition{{Column: "name", Op: "=", Value: "Ann Lee"}}},
+		{`name="x;drop"`, []Condition{{Column: "name", Op: "=", Value: "x;drop"}}},
+		{"name=Ann", []Condition{{Column: "name", Op: "=", Value: "Ann"}}},
+		{`email="  Ann@Example.com "`, []Condition{{Column: "email", Op: "=", Value: "ann@example.com"}}},
+		{"name~ann", []Condition{{Column: "name", Op: "ILIKE", Value: "%ann%"}}},
+		{`name~"50%_off\\"`, []Condition{{Column: "name", Op: "ILIKE", Value: `%50\%\_off\\%`}}},
+		{"created_at>=2024-05-01", []Condition{{Column: "created_at", Op: ">=", Value: day}}},
+		{"created_at<2024-05-01T12:30:00Z", []Condition{{Column: "created_at", Op: "<", Value: day.Add(12*time.Hour + 30*time.Minute)}}},
+		{"age>18 and name=ann", []Condition{{Column: "age", Op: ">", Value: int64(18)}, {Column: "name", Op: "=", Value: "ann"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := Parse(tt.expr, testFields)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+	}{
+		{"unknown field", "password=x"},
+		{"quoted field", `"age">=18`},
+		{"column expression as field", "age+0>=18"},
+		{"unknown operator", "age=>18"},
+		{"SQL operator", "name LIKE x"},
+		{"quoted operator", `age">="18`},
+		{"substring match on a number", "age~1"},
+		{"ordering on a boolean", "verified>false"},
+		{"invalid number", "age=eighteen"},
+		{"invalid boolean", "verified=yes please"},
+		{"invalid time", "created_at>=yesterday"},
+		{"incomplete condition", "age>="},
+		{"OR instead of AND", "age>18 OR name=ann"},
+		{"quoted AND", `age>18 "AND" name=ann`},
+		{"statement after a value", "name=x;drop"},
+		{"SQL comment", "name=x'--"},
+		{"always true clause", "name=x OR 1=1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conds, err := Parse(tt.expr, testFields)
+			var filterErr *Error
+			assert.ErrorAs(t, err, &filterErr)
+			assert.Nil(t, conds)
+		})
+	}
+}
+
+func TestScopeBindsValues(t *testing.T) {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	require.NoError(t, err)
+	conds, err := Parse(`name="x'; DROP TABLE users; --" AND age>=18`, testFields)
+	require.NoError(t, err)
+
+	var rows []map[string]any
+	stmt := db.Table("users").Scopes(Scope(conds)).Find(&rows).Statement
+	assert.Equal(t, `SELECT * FROM "users" WHERE name = $1 AND age >= $2`, stmt.SQL.String())
+	assert.Equal(t, []any{"x'; DROP TABLE users; --", int64(18)}, stmt.Vars)
+}
@@ -0,0 +1,170 @@
+// Package fixtures dumps and loads the whole dataset as a single versioned
+// JSON document, to copy one environment into another or reproduce a bug
+// locally. Unlike backups, fixtures leave out the audit log, whose hash chain
+// belongs to the environment that wrote it, and the outbox and export jobs,
+// which only make sense where they were queued.
+package fixtures
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/rkgcloud/crud/pkg/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Version is the version of the document Export writes and Import reads
+const Version = 1
+
+// batchSize is the number of rows inserted per statement
+const batchSize = 500
+
+// Tables are the models whose tables fixtures hold, in load order
+var Tables = []any{&models.User{}, &models.Address{}, &models.Invitation{}, &models.Tag{}}
+
+// Document is a fixtures file
+type Document struct {
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exported_at"`
+	// Tables holds the rows of every table, by table name, as column values
+	Tables map[string][]map[string]any `json:"tables"`
+}
+
+// ErrNotEmpty is returned by Import when the tables already hold rows and
+// replacing them was not asked for
+var ErrNotEmpty = errors.New("the database already holds data")
+
+// Export writes every row of Tables to w
+func Export(ctx context.Context, db *gorm.DB, w io.Writer) (Document, error) {
+	db = db.WithContext(ctx)
+	tables, err := tableNames(db)
+	if err != nil {
+		return Document{}, err
+	}
+	doc := Document{Version: Version, ExportedAt: time.Now().UTC(), Tables: map[string][]map[string]any{}}
+	for _, table := range tables {
+		rows := []map[string]any{}
+		if err := db.Table(table).Order("id").Find(&rows).Error; err != nil {
+			return doc, fmt.Errorf("exporting %s: %w", table, err)
+		}
+		// jsonb columns are scanned as bytes; keep them as JSON
+		for _, row := range rows {
+			for column, value := range row {
+				if b, ok := value.([]byte); ok && json.Valid(b) {
+					row[column] = json.RawMessage(b)
+				}
+			}
+		}
+		doc.Tables[table] = rows
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return doc, enc.Encode(doc)
+}
+
+// Import loads the document read from r into Tables within one transaction.
+// The tables must be empty unless replace is set, in which case their rows are
+// deleted first. Sequences continue after the loaded IDs.
+func Import(ctx context.Context, db *gorm.DB, r io.Reader, replace bool) (Document, error) {
+	dec := json.NewDecoder(r)
+	// Numbers stay exact, so IDs are not rounded through float64
+	dec.UseNumber()
+	var doc Document
+	if err := dec.Decode(&doc); err != nil {
+		return doc, fmt.Errorf("not a fixtures document: %w", err)
+	}
+	if doc.Version != Version {
+		return doc, fmt.Errorf("unsupported fixtures version %d, expected %d", doc.Version, Version)
+	}
+	db = db.WithContext(ctx)
+	tables, err := tableNames(db)
+	if err != nil {
+		return doc, err
+	}
+	for table := range doc.Tables {
+		if !slices.Contains(tables, table) {
+			return doc, fmt.Errorf("fixtures hold unknown table %q", table)
+		}
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if replace {
+			// Deleting in reverse load order keeps foreign keys satisfied
+			for i := len(tables) - 1; i >= 0; i-- {
+				if err := tx.Exec("DELETE FROM ?", clause.Table{Name: tables[i]}).Error; err != nil {
+					return fmt.Errorf("clearing %s: %w", tables[i], err)
+				}
+			}
+		}
+		for _, table := range tables {
+			var count int64
+			if err := tx.Table(table).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				return fmt.Errorf("%w: %s has %d rows", ErrNotEmpty, table, count)
+			}
+		}
+		for _, table := range tables {
+			rows := doc.Tables[table]
+			if len(rows) == 0 {
+				continue
+			}
+			values := make([]map[string]any, 0, len(rows))
+			for _, row := range rows {
+				values = append(values, columnValues(row))
+			}
+			if err := tx.Table(table).CreateInBatches(values, batchSize).Error; err != nil {
+				return fmt.Errorf("loading %s: %w", table, err)
+			}
+			// Rows were inserted with their IDs, which the sequence did not hand out
+			if err := tx.Exec("SELECT setval(pg_get_serial_sequence(?, 'id'), MAX(id)) FROM ?", table, clause.Table{Name: table}).Error; err != nil {
+				return fmt.Errorf("resetting the %s sequence: %w", table, err)
+			}
+		}
+		return nil
+	})
+	return doc, err
+}
+
+// tableNames returns the names of the tables of Tables
+func tableNames(db *gorm.DB) ([]string, error) {
+	tables := make([]string, 0, len(Tables))
+	for _, model := range Tables {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return nil, err
+		}
+		tables = append(tables, stmt.Schema.Table)
+	}
+	return tables, nil
+}
+
+// columnValues converts a decoded row into values the driver accepts: numbers
+// become int64 or float64 and objects, such as jsonb columns, JSON again
+func columnValues(row map[string]any) map[string]any {
+	values := make(map[string]any, len(row))
+	for column, value := range row {
+		switch v := value.(type) {
+		case json.Number:
+			if n, err := v.Int64(); err == nil {
+				values[column] = n
+			} else {
+				values[column], _ = v.Float64()
+			}
+		case map[string]any, []any:
+			data, _ := json.Marshal(v)
+			values[column] = string(data)
+		default:
+			values[column] = v
+		}
+	}
+	return values
+}
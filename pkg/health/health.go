@@ -0,0 +1,273 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/rkgcloud/crud/pkg/api/render"
+	"github.com/rkgcloud/crud/pkg/config"
+	"github.com/rkgcloud/crud/pkg/version"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Status is the health state of a single check or the whole service
+type Status string
+
+const (
+	StatusUp       Status = "up"
+	StatusDegraded Status = "degraded"
+	StatusDown     Status = "down"
+)
+
+// CheckFunc reports the health of a subsystem. A nil error means up, an error
+// wrapped with Degraded means degraded and any other error means down.
+type CheckFunc func(ctx context.Context) error
+
+// CheckResult is the outcome of running a single check
+type CheckResult struct {
+	Status    Status  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Report is the aggregated outcome of all registered checks
+type Report struct {
+	Status    Status                 `json:"status"`
+	Timestamp time.Time              `json:"timestamp"`
+	Version   version.Info           `json:"version"`
+	Checks    map[string]CheckResult `json:"checks"`
+}
+
+// degradedError marks a check failure as non-fatal
+type degradedError struct {
+	err error
+}
+
+func (e degradedError) Error() string { return e.err.Error() }
+func (e degradedError) Unwrap() error { return e.err }
+
+// Degraded wraps err so the check is reported as degraded instead of down
+func Degraded(err error) error {
+	return degradedError{err: err}
+}
+
+// registeredCheck is a check together with the time it is allowed to take
+type registeredCheck struct {
+	check   CheckFunc
+	timeout time.Duration
+}
+
+// HealthChecker runs the registered health checks
+type HealthChecker struct {
+	mu     sync.RWMutex
+	db     *gorm.DB
+	cfg    config.HealthConfig
+	checks map[string]registeredCheck
+	// last is the status of the previous Check, "" before the first one
+	last          Status
+	onTransitions []func(from Status, report Report)
+}
+
+// NewHealthChecker creates a HealthChecker with the database and memory checks registered
+func NewHealthChecker(db *gorm.DB, cfg config.HealthConfig) *HealthChecker {
+	h := &HealthChecker{db: db, cfg: cfg, checks: map[string]registeredCheck{}}
+	h.registerBuiltins()
+	return h
+}
+
+// OnConfigReload applies new thresholds and timeouts to the built-in checks
+func (h *HealthChecker) OnConfigReload(cfg *config.Config) {
+	h.mu.Lock()
+	h.cfg = cfg.Health
+	h.mu.Unlock()
+	h.registerBuiltins()
+}
+
+// registerBuiltins (re)registers the database and memory checks from the current config
+func (h *HealthChecker) registerBuiltins() {
+	h.mu.RLock()
+	cfg := h.cfg
+	h.mu.RUnlock()
+	h.RegisterWithTimeout("database", cfg.DatabaseTimeout, DatabaseCheck(h.db))
+	h.Register("memory", MemoryCheck(cfg.MemoryWarnMB<<20, cfg.MemoryCriticalMB<<20))
+}
+
+// Register adds a named check using the default check timeout, replacing any
+// existing check with the same name
+func (h *HealthChecker) Register(name string, check CheckFunc) {
+	h.mu.RLock()
+	timeout := h.cfg.CheckTimeout
+	h.mu.RUnlock()
+	h.RegisterWithTimeout(name, timeout, check)
+}
+
+// RegisterWithTimeout adds a named check that is reported down when it takes longer than timeout
+func (h *HealthChecker) RegisterWithTimeout(name string, timeout time.Duration, check CheckFunc) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks[name] = registeredCheck{check: check, timeout: timeout}
+}
+
+// OnTransition registers fn to be called with the previous status and the new
+// report whenever a check reports a status different from the one before
+func (h *HealthChecker) OnTransition(fn func(from Status, report Report)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.onTransitions = append(h.onTransitions, fn)
+}
+
+// Check runs every registered check concurrently and aggregates the results
+func (h *HealthChecker) Check(ctx context.Context) Report {
+	h.mu.RLock()
+	checks := make(map[string]registeredCheck, len(h.checks))
+	for name, check := range h.checks {
+		checks[name] = check
+	}
+	h.mu.RUnlock()
+
+	report := Report{
+		Status:    StatusUp,
+		Timestamp: time.Now().UTC(),
+		Version:   version.Get(),
+		Checks:    make(map[string]CheckResult, len(checks)),
+	}
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for name, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := run(ctx, check.check, check.timeout)
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[name] = result
+			report.Status = worst(report.Status, result.Status)
+		}()
+	}
+	wg.Wait()
+	h.transition(report)
+	return report
+}
+
+// transition records the status of report and notifies the OnTransition
+// functions when it changed
+func (h *HealthChecker) transition(report Report) {
+	h.mu.Lock()
+	from := h.last
+	h.last = report.Status
+	fns := h.onTransitions
+	h.mu.Unlock()
+	if from == "" || from == report.Status {
+		return
+	}
+	for _, fn := range fns {
+		fn(from, report)
+	}
+}
+
+// Health reports the result of every check, responding 503 when the service is down
+func (h *HealthChecker) Health(c *gin.Context) error {
+	report := h.Check(c.Request.Context())
+	return render.JSON(c, statusCode(report.Status), report)
+}
+
+// Live reports that the process is running
+func (h *HealthChecker) Live(c *gin.Context) error {
+	return render.JSON(c, http.StatusOK, gin.H{"status": StatusUp, "version": version.Get()})
+}
+
+// Ready reports whether the service can accept traffic
+func (h *HealthChecker) Ready(c *gin.Context) error {
+	report := h.Check(c.Request.Context())
+	return render.JSON(c, statusCode(report.Status), gin.H{"status": report.Status, "version": report.Version})
+}
+
+// Version reports the build information of the running binary
+func (h *HealthChecker) Version(c *gin.Context) error {
+	return render.JSON(c, http.StatusOK, version.Get())
+}
+
+// DatabaseCheck pings the database behind db
+func DatabaseCheck(db *gorm.DB) CheckFunc {
+	return func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	}
+}
+
+// MemoryCheck reports degraded when the heap grows past warnBytes and down past criticalBytes
+func MemoryCheck(warnBytes, criticalBytes uint64) CheckFunc {
+	return func(ctx context.Context) error {
+		var m runtime.MemStats
+		runtime.ReadMemStats(&m)
+		switch {
+		case m.HeapAlloc >= criticalBytes:
+			return fmt.Errorf("heap allocation %d MB exceeds %d MB", m.HeapAlloc>>20, criticalBytes>>20)
+		case m.HeapAlloc >= warnBytes:
+			return Degraded(fmt.Errorf("heap allocation %d MB exceeds %d MB", m.HeapAlloc>>20, warnBytes>>20))
+		}
+		return nil
+	}
+}
+
+// run executes check and records its latency. The check is abandoned once
+// timeout elapses so a hanging check cannot stall the caller.
+func run(ctx context.Context, check CheckFunc, timeout time.Duration) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() { done <- check(ctx) }()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("check timed out after %s", timeout)
+	}
+	result := CheckResult{
+		Status:    StatusUp,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Error = err.Error()
+		result.Status = StatusDown
+		var degraded degradedError
+		if errors.As(err, &degraded) {
+			result.Status = StatusDegraded
+		}
+	}
+	return result
+}
+
+// worst returns the more severe of two statuses
+func worst(a, b Status) Status {
+	if a == StatusDown || b == StatusDown {
+		return StatusDown
+	}
+	if a == StatusDegraded || b == StatusDegraded {
+		return StatusDegraded
+	}
+	return StatusUp
+}
+
+// statusCode maps a status to the HTTP status code returned to probes
+func statusCode(s Status) int {
+	if s == StatusDown {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusOK
+}
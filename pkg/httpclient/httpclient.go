@@ -0,0 +1,123 @@
+// Package httpclient makes the outbound HTTP calls of the service, bounding
+// every attempt with a timeout and retrying transient failures with jittered
+// exponential backoff. Cancelling the request context stops the call,
+// including any backoff in progress.
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"time"
+)
+
+// baseDelay is the backoff before the first retry; it doubles with every retry
+const baseDelay = 200 * time.Millisecond
+
+// Client sends requests with a timeout per attempt and bounded retries
+type Client struct {
+	http    *http.Client
+	timeout time.Duration
+	retries int
+}
+
+// New creates a Client giving each attempt timeout and retrying a failed
+// request up to retries times
+func New(timeout time.Duration, retries int) *Client {
+	return &Client{http: &http.Client{}, timeout: timeout, retries: retries}
+}
+
+// Do sends req and returns the response of the first attempt that did not
+// fail transiently. Connection errors and 429, 502, 503 and 504 responses are
+// retried; a request with a body is only retried when it can be rewound
+// through GetBody, as http.NewRequest sets up for in-memory bodies.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	for attempt := 0; ; attempt++ {
+		resp, err := c.attempt(ctx, req)
+		if attempt == c.retries || !retryable(resp, err) || (req.Body != nil && req.GetBody == nil) {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+		timer := time.NewTimer(backoff(attempt))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Post sends body to url with the given content type
+func (c *Client) Post(ctx context.Context, url, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	return c.Do(req)
+}
+
+// CloseIdleConnections closes the connections kept alive for later calls
+func (c *Client) CloseIdleConnections() {
+	c.http.CloseIdleConnections()
+}
+
+// attempt sends req once, within the timeout. The timeout keeps running while
+// the response body is read and ends when it is closed.
+func (c *Client) attempt(ctx context.Context, req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	resp, err := c.http.Do(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		if ctx.Err() == context.DeadlineExceeded && req.Context().Err() == nil {
+			err = fmt.Errorf("no response within %s: %w", c.timeout, err)
+		}
+		return nil, err
+	}
+	resp.Body = cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// retryable reports whether an attempt failed in a way another attempt may not
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// backoff is a random delay of up to baseDelay doubled attempt times, so
+// clients failing together do not retry together
+func backoff(attempt int) time.Duration {
+	return rand.N(baseDelay << attempt)
+}
+
+// cancelOnClose releases the context of an attempt once its body is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b cancelOnClose) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
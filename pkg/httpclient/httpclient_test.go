@@ -0,0 +1,132 @@
+package httpclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recorder is a server answering attempts with the statuses of respond in
+// turn, the last one repeating, and recording the body of each attempt
+type recorder struct {
+	mu      sync.Mutex
+	bodies  []string
+	respond []int
+	// stall delays the answer to the first attempt
+	stall time.Duration
+}
+
+func (s *recorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	s.mu.Lock()
+	attempt := len(s.bodies)
+	s.bodies = append(s.bodies, string(body))
+	status := s.respond[min(attempt, len(s.respond)-1)]
+	s.mu.Unlock()
+	if attempt == 0 && s.stall > 0 {
+		select {
+		case <-time.After(s.stall):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	w.WriteHeader(status)
+}
+
+func (s *recorder) attempts() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.bodies
+}
+
+func TestClientRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		respond  []int
+		retries  int
+		status   int
+		attempts int
+	}{
+		{name: "success", respond: []int{http.StatusOK}, retries: 2, status: http.StatusOK, attempts: 1},
+		{name: "client error", respond: []int{http.StatusBadRequest}, retries: 2, status: http.StatusBadRequest, attempts: 1},
+		{name: "recovers", respond: []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusOK}, retries: 2, status: http.StatusOK, attempts: 3},
+		{name: "gives up", respond: []int{http.StatusServiceUnavailable}, retries: 2, status: http.StatusServiceUnavailable, attempts: 3},
+		{name: "no retries", respond: []int{http.StatusGatewayTimeout}, retries: 0, status: http.StatusGatewayTimeout, attempts: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &recorder{respond: tt.respond}
+			ts := httptest.NewServer(server)
+			defer ts.Close()
+
+			resp, err := New(time.Second, tt.retries).Post(context.Background(), ts.URL, "text/plain", strings.NewReader("payload"))
+			require.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, tt.status, resp.StatusCode)
+			// Every attempt sends the whole body again
+			bodies := server.attempts()
+			assert.Len(t, bodies, tt.attempts)
+			for _, body := range bodies {
+				assert.Equal(t, "payload", body)
+			}
+		})
+	}
+}
+
+func TestClientDoesNotRetryBodyItCannotRewind(t *testing.T) {
+	server := &recorder{respond: []int{http.StatusServiceUnavailable, http.StatusOK}}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	// http.NewRequest only sets GetBody for in-memory readers
+	req, err := http.NewRequest(http.MethodPost, ts.URL, io.MultiReader(strings.NewReader("payload")))
+	require.NoError(t, err)
+	resp, err := New(time.Second, 2).Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Len(t, server.attempts(), 1)
+}
+
+func TestClientTimesOutEachAttempt(t *testing.T) {
+	server := &recorder{respond: []int{http.StatusOK}, stall: time.Second}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	// The stalled first attempt times out and the retry gets its own timeout
+	resp, err := New(100*time.Millisecond, 1).Post(context.Background(), ts.URL, "text/plain", strings.NewReader("payload"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Len(t, server.attempts(), 2)
+
+	server = &recorder{respond: []int{http.StatusOK}, stall: time.Second}
+	ts2 := httptest.NewServer(server)
+	defer ts2.Close()
+	_, err = New(100*time.Millisecond, 0).Post(context.Background(), ts2.URL, "text/plain", strings.NewReader("payload"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no response within 100ms")
+}
+
+func TestBackoff(t *testing.T) {
+	for attempt := 0; attempt < 4; attempt++ {
+		limit := baseDelay << attempt
+		seen := map[time.Duration]bool{}
+		for i := 0; i < 100; i++ {
+			delay := backoff(attempt)
+			assert.GreaterOrEqual(t, delay, time.Duration(0))
+			assert.Less(t, delay, limit)
+			seen[delay] = true
+		}
+		// The delay is jittered rather than fixed
+		assert.Greater(t, len(seen), 1, "attempt %d", attempt)
+	}
+}
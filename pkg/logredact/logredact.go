@@ -0,0 +1,100 @@
+// Package logredact masks personal data and secrets in log output
+package logredact
+
+import (
+	"io"
+	"regexp"
+
+	"github.com/rkgcloud/crud/pkg/config"
+)
+
+const redacted = "REDACTED"
+
+// rule replaces the matches of pattern with replacement, or strict in strict mode
+type rule struct {
+	pattern     *regexp.Regexp
+	replacement string
+	strict      string
+}
+
+// rules are the masks of every field. Outside strict mode, emails keep their
+// first letter and domain and phone numbers their last two digits, so entries
+// can still be told apart.
+var rules = map[string][]rule{
+	config.LogFieldEmail: {{
+		pattern:     regexp.MustCompile(`([A-Za-z0-9._%+-])[A-Za-z0-9._%+-]*@([A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,})`),
+		replacement: "${1}***@${2}",
+		strict:      redacted,
+	}},
+	config.LogFieldPhone: {{
+		pattern:     regexp.MustCompile(`\+[1-9](?:[ ().-]*\d){4,12}((?:[ ().-]*\d){2})`),
+		replacement: "+***${1}",
+		strict:      redacted,
+	}},
+	config.LogFieldToken: {
+		{
+			pattern:     regexp.MustCompile(`(?i)(\b(?:token|access_token|refresh_token|api_key)"?\s*[=:]\s*"?)[^\s"'&,;]+`),
+			replacement: "${1}" + redacted,
+			strict:      "${1}" + redacted,
+		},
+		{
+			pattern:     regexp.MustCompile(`(?i)(\bbearer\s+)[A-Za-z0-9._~+/=-]+`),
+			replacement: "${1}" + redacted,
+			strict:      "${1}" + redacted,
+		},
+	},
+	config.LogFieldSession: {{
+		pattern:     regexp.MustCompile(`(?i)(\b(?:session|session_?id|sid)"?\s*[=:]\s*"?)[^\s"'&,;]+`),
+		replacement: "${1}" + redacted,
+		strict:      "${1}" + redacted,
+	}},
+}
+
+// Redactor masks the configured fields in text
+type Redactor struct {
+	rules  []rule
+	strict bool
+}
+
+// New creates a Redactor masking cfg.Fields, or every field in strict mode
+func New(cfg config.LogRedactConfig) *Redactor {
+	fields := cfg.Fields
+	if cfg.Strict {
+		fields = config.LogFields
+	}
+	r := &Redactor{strict: cfg.Strict}
+	for _, field := range fields {
+		r.rules = append(r.rules, rules[field]...)
+	}
+	return r
+}
+
+// Redact returns s with the configured fields masked
+func (r *Redactor) Redact(s string) string {
+	for _, rule := range r.rules {
+		replacement := rule.replacement
+		if r.strict {
+			replacement = rule.strict
+		}
+		s = rule.pattern.ReplaceAllString(s, replacement)
+	}
+	return s
+}
+
+// Writer returns a writer masking what is written before passing it to w.
+// Log entries must be written in a single call, as the log and slog packages do.
+func (r *Redactor) Writer(w io.Writer) io.Writer {
+	return writer{redactor: r, w: w}
+}
+
+type writer struct {
+	redactor *Redactor
+	w        io.Writer
+}
+
+func (w writer) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(w.w, w.redactor.Redact(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
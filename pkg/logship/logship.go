@@ -0,0 +1,259 @@
+// Package logship ships log entries to Loki or Elasticsearch in batches.
+//
+// Entries are buffered in memory and sent in the background, so a slow or
+// unreachable sink never blocks logging: once the buffer is full, entries are
+// dropped and the number dropped is reported on stderr.
+package logship
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rkgcloud/crud/pkg/config"
+	"github.com/rkgcloud/crud/pkg/httpclient"
+)
+
+// entry is a single log line
+type entry struct {
+	time   time.Time
+	source string
+	line   string
+}
+
+// Shipper buffers log entries and sends them to the configured sink
+type Shipper struct {
+	cfg    config.LogSinkConfig
+	labels map[string]string
+	client *httpclient.Client
+	queue  chan entry
+	// dropped counts the entries lost since it was last reported
+	dropped atomic.Int64
+	// closed stops Write from queueing once Close has started
+	mu     sync.RWMutex
+	closed bool
+	done   chan struct{}
+}
+
+// New creates a Shipper sending to the sink of cfg, labelling every entry
+// with labels. It returns nil when no sink is configured.
+func New(cfg config.LogSinkConfig, labels map[string]string) *Shipper {
+	if cfg.Type == config.LogSinkNone {
+		return nil
+	}
+	s := &Shipper{
+		cfg:    cfg,
+		labels: labels,
+		client: httpclient.New(10*time.Second, 2),
+		queue:  make(chan entry, cfg.BufferSize),
+		done:   make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+// Writer returns a writer shipping every line written to it as an entry of
+// source, such as "app" or "access"
+func (s *Shipper) Writer(source string) io.Writer {
+	return writer{shipper: s, source: source}
+}
+
+type writer struct {
+	shipper *Shipper
+	source  string
+}
+
+func (w writer) Write(p []byte) (int, error) {
+	now := time.Now()
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		if line != "" {
+			w.shipper.add(entry{time: now, source: w.source, line: line})
+		}
+	}
+	return len(p), nil
+}
+
+// add queues e without blocking, dropping it when the buffer is full
+func (s *Shipper) add(e entry) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return
+	}
+	select {
+	case s.queue <- e:
+	default:
+		s.dropped.Add(1)
+	}
+}
+
+// Close stops accepting entries and waits until the buffered ones are sent or ctx is done
+func (s *Shipper) Close(ctx context.Context) error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	s.closed = true
+	close(s.queue)
+	s.mu.Unlock()
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%d log entries not shipped: %w", len(s.queue), ctx.Err())
+	}
+}
+
+// run sends batches until the queue is closed and drained
+func (s *Shipper) run() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.cfg.FlushInterval)
+	defer ticker.Stop()
+	batch := make([]entry, 0, s.cfg.BatchSize)
+	for {
+		flush := false
+		select {
+		case e, ok := <-s.queue:
+			if !ok {
+				s.send(batch)
+				return
+			}
+			batch = append(batch, e)
+			flush = len(batch) == s.cfg.BatchSize
+		case <-ticker.C:
+			flush = len(batch) > 0
+		}
+		if flush {
+			s.send(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+// send ships batch. Failures are reported on stderr rather than logged, as
+// the log is what is being shipped.
+func (s *Shipper) send(batch []entry) {
+	if n := s.dropped.Swap(0); n > 0 {
+		fmt.Fprintf(os.Stderr, "log sink buffer full, dropped %d log entries\n", n)
+	}
+	if len(batch) == 0 {
+		return
+	}
+	if err := s.post(batch); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to ship %d log entries to %s: %v\n", len(batch), s.cfg.Type, err)
+	}
+}
+
+// post sends batch in a single request in the format of the sink
+func (s *Shipper) post(batch []entry) error {
+	var (
+		path, contentType string
+		body              []byte
+		err               error
+	)
+	switch s.cfg.Type {
+	case config.LogSinkLoki:
+		path, contentType = "/loki/api/v1/push", "application/json"
+		body, err = s.lokiBody(batch)
+	default:
+		path, contentType = "/_bulk", "application/x-ndjson"
+		body, err = s.bulkBody(batch)
+	}
+	if err != nil {
+		return err
+	}
+	resp, err := s.client.Post(context.Background(), strings.TrimSuffix(s.cfg.URL, "/")+path, contentType, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s responded with %s", s.cfg.Type, resp.Status)
+	}
+	if s.cfg.Type == config.LogSinkElasticsearch {
+		return bulkErrors(resp.Body)
+	}
+	return nil
+}
+
+// lokiBody groups batch into one stream per source, as the Loki push API expects
+func (s *Shipper) lokiBody(batch []entry) ([]byte, error) {
+	type stream struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	}
+	var streams []*stream
+	bySource := map[string]*stream{}
+	for _, e := range batch {
+		st, ok := bySource[e.source]
+		if !ok {
+			st = &stream{Stream: map[string]string{"source": e.source}}
+			for k, v := range s.labels {
+				st.Stream[k] = v
+			}
+			bySource[e.source] = st
+			streams = append(streams, st)
+		}
+		st.Values = append(st.Values, [2]string{strconv.FormatInt(e.time.UnixNano(), 10), e.line})
+	}
+	return json.Marshal(map[string]any{"streams": streams})
+}
+
+// bulkBody writes batch as the action and document lines of a bulk request
+func (s *Shipper) bulkBody(batch []entry) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	action := map[string]any{"create": map[string]string{"_index": s.cfg.Index}}
+	for _, e := range batch {
+		doc := map[string]string{
+			"@timestamp": e.time.UTC().Format(time.RFC3339Nano),
+			"message":    e.line,
+			"source":     e.source,
+		}
+		for k, v := range s.labels {
+			doc[k] = v
+		}
+		if err := enc.Encode(action); err != nil {
+			return nil, err
+		}
+		if err := enc.Encode(doc); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// bulkErrors reports the documents a bulk response rejected
+func bulkErrors(r io.Reader) error {
+	var resp struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Error *struct {
+				Reason string `json:"reason"`
+			} `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(r).Decode(&resp); err != nil || !resp.Errors {
+		return err
+	}
+	failed, reason := 0, ""
+	for _, item := range resp.Items {
+		for _, result := range item {
+			if result.Error != nil {
+				failed++
+				reason = result.Error.Reason
+			}
+		}
+	}
+	// Rejected documents would be rejected again, so they are not retried
+	fmt.Fprintf(os.Stderr, "elasticsearch rejected %d log entries: %s\n", failed, reason)
+	return nil
+}
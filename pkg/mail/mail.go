@@ -0,0 +1,252 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"embed"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	crudtemplates "github.com/rkgcloud/crud/pkg/templates"
+)
+
+// ErrQueueFull is returned when a message cannot be queued without blocking
+var ErrQueueFull = errors.New("mail queue is full")
+
+// ErrClosed is returned when a message is queued after the Mailer was closed
+var ErrClosed = errors.New("mail queue is closed")
+
+// sendTimeout bounds an SMTP conversation whose context has no deadline
+const sendTimeout = 30 * time.Second
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+// templates are rendered to a subject line, a blank line and the plain-text body
+var templates = template.Must(template.New("mail").Funcs(crudtemplates.Funcs()).ParseFS(templateFS, "templates/*.tmpl"))
+
+// Message is a plain-text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers a single message
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// SMTPSender delivers messages through an SMTP relay, upgrading to TLS with
+// STARTTLS when the server offers it
+type SMTPSender struct {
+	host string
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+// NewSMTPSender creates an SMTPSender for host:port. Authentication is only
+// used when username is set.
+func NewSMTPSender(host string, port int, username, password, from string) *SMTPSender {
+	s := &SMTPSender{host: host, addr: net.JoinHostPort(host, fmt.Sprint(port)), from: from}
+	if username != "" {
+		s.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return s
+}
+
+// Send delivers msg. The whole conversation with the relay, from dialing to
+// QUIT, ends at the deadline of ctx.
+func (s *SMTPSender) Send(ctx context.Context, msg Message) error {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", s.from)
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	buf.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(sendTimeout)
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+	// Cancelling ctx before its deadline interrupts the conversation too
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	c, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: s.host}); err != nil {
+			return err
+		}
+	}
+	if s.auth != nil {
+		if err := c.Auth(s.auth); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(s.from); err != nil {
+		return err
+	}
+	if err := c.Rcpt(msg.To); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// LogSender writes messages to the log instead of sending them, for development
+type LogSender struct{}
+
+// Send logs msg
+func (LogSender) Send(ctx context.Context, msg Message) error {
+	log.Printf("mail to %s: %s\n%s\n", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// Mailer renders templated emails and sends them asynchronously from a queue
+type Mailer struct {
+	sender Sender
+	queue  chan Message
+	wg     sync.WaitGroup
+
+	// mu guards closed, so no message is queued once Close closed the queue
+	mu     sync.Mutex
+	closed bool
+}
+
+// NewMailer creates a Mailer sending through sender with workers goroutines
+// draining a queue of queueSize messages
+func NewMailer(sender Sender, queueSize, workers int) *Mailer {
+	m := &Mailer{sender: sender, queue: make(chan Message, queueSize)}
+	for i := 0; i < workers; i++ {
+		m.wg.Add(1)
+		go m.work()
+	}
+	return m
+}
+
+// SendVerification queues the email verification link for a new user
+func (m *Mailer) SendVerification(to, name, link string, expiresAt time.Time) error {
+	return m.enqueue(to, "verification.tmpl", map[string]any{
+		"Name":      name,
+		"Link":      link,
+		"ExpiresAt": expiresAt,
+	})
+}
+
+// SendInvitation queues an invitation link
+func (m *Mailer) SendInvitation(to, role, link string, expiresAt time.Time) error {
+	return m.enqueue(to, "invitation.tmpl", map[string]any{
+		"Role":      role,
+		"Link":      link,
+		"ExpiresAt": expiresAt,
+	})
+}
+
+// Close stops accepting messages and waits until the queue is drained or ctx is done
+func (m *Mailer) Close(ctx context.Context) error {
+	m.mu.Lock()
+	if !m.closed {
+		m.closed = true
+		close(m.queue)
+	}
+	m.mu.Unlock()
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%d messages not sent: %w", len(m.queue), ctx.Err())
+	}
+}
+
+// CheckTemplates renders every email with sample data, reporting the first
+// template that fails or lacks a subject
+func CheckTemplates() error {
+	data := map[string]any{
+		"Name":      "Jane Doe",
+		"Role":      "user",
+		"Link":      "https://example.com/link",
+		"ExpiresAt": time.Now(),
+	}
+	for _, t := range templates.Templates() {
+		var buf bytes.Buffer
+		if err := t.Execute(&buf, data); err != nil {
+			return fmt.Errorf("rendering %s: %w", t.Name(), err)
+		}
+		if subject, _, _ := strings.Cut(buf.String(), "\n\n"); strings.TrimSpace(subject) == "" {
+			return fmt.Errorf("%s has no subject line", t.Name())
+		}
+	}
+	return nil
+}
+
+// enqueue renders the named template for to and queues the result
+func (m *Mailer) enqueue(to, name string, data any) error {
+	var buf bytes.Buffer
+	if err := templates.ExecuteTemplate(&buf, name, data); err != nil {
+		return fmt.Errorf("rendering %s: %w", name, err)
+	}
+	subject, body, _ := strings.Cut(buf.String(), "\n\n")
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return ErrClosed
+	}
+	select {
+	case m.queue <- Message{To: to, Subject: strings.TrimSpace(subject), Body: body}:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// work sends queued messages until the queue is closed
+func (m *Mailer) work() {
+	defer m.wg.Done()
+	for msg := range m.queue {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		if err := m.sender.Send(ctx, msg); err != nil {
+			log.Printf("failed to send %q to %s: %v\n", msg.Subject, msg.To, err)
+		}
+		cancel()
+	}
+}
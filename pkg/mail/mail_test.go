@@ -0,0 +1,44 @@
+package mail
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMailerQueueAfterCloseIsRejected(t *testing.T) {
+	m := NewMailer(LogSender{}, 1, 1)
+	require.NoError(t, m.Close(context.Background()))
+
+	assert.ErrorIs(t, m.SendVerification("ann@example.com", "Ann", "https://example.com/link", time.Now()), ErrClosed)
+	assert.NoError(t, m.Close(context.Background()))
+}
+
+func TestSMTPSenderHonorsContext(t *testing.T) {
+	// The relay accepts the connection but never greets
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	addr := ln.Addr().(*net.TCPAddr)
+	s := NewSMTPSender("127.0.0.1", addr.Port, "", "", "noreply@example.com")
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = s.Send(ctx, Message{To: "ann@example.com", Subject: "Hi", Body: "Hello"})
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
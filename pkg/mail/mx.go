@@ -0,0 +1,33 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// ErrNoMailServer is returned when a domain does not accept email
+var ErrNoMailServer = errors.New("domain does not accept email")
+
+// CheckMX reports whether domain has a mail server. Domains without MX records
+// fall back to their address records, and a null MX ("." per RFC 7505) refuses
+// mail explicitly.
+func CheckMX(ctx context.Context, domain string) error {
+	var resolver net.Resolver
+	records, err := resolver.LookupMX(ctx, domain)
+	if err == nil && len(records) > 0 {
+		if len(records) == 1 && records[0].Host == "." {
+			return ErrNoMailServer
+		}
+		return nil
+	}
+	var dnsErr *net.DNSError
+	if err != nil && !(errors.As(err, &dnsErr) && dnsErr.IsNotFound) {
+		return fmt.Errorf("looking up MX records of %s: %w", domain, err)
+	}
+	if addrs, err := resolver.LookupHost(ctx, domain); err == nil && len(addrs) > 0 {
+		return nil
+	}
+	return ErrNoMailServer
+}
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/rkgcloud/crud/pkg/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminAuth only lets through requests carrying "Authorization: Bearer <token>".
+// When token is empty admin access is disabled and every request is rejected.
+func AdminAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			problem.Abort(c, http.StatusForbidden, "Admin access is not configured")
+			return
+		}
+		if !isAdmin(c, token) {
+			problem.Abort(c, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		c.Next()
+	}
+}
+
+// isAdmin reports whether the request carries the admin token, which must not be empty
+func isAdmin(c *gin.Context, token string) bool {
+	provided, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	return ok && token != "" && subtle.ConstantTimeCompare([]byte(provided), []byte(token)) == 1
+}
+
+// inNets reports whether ip is inside one of nets
+func inNets(ip string, nets []*net.IPNet) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, n := range nets {
+		if n.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// AdminOrAllowlist lets through requests whose peer address is inside one of
+// nets and authenticates everything else with AdminAuth. The peer address is
+// used rather than forwarded headers so the allowlist cannot be spoofed.
+func AdminOrAllowlist(token string, nets []*net.IPNet) gin.HandlerFunc {
+	admin := AdminAuth(token)
+	return func(c *gin.Context) {
+		if inNets(c.RemoteIP(), nets) {
+			c.Next()
+			return
+		}
+		admin(c)
+	}
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rkgcloud/crud/pkg/alert"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Alerts reports panics and bursts of server errors to alerter. A burst is
+// threshold responses with a 5xx status within window; a threshold of 0 only
+// reports panics. It must come after gin.Recovery, which still answers the
+// panicking request.
+func Alerts(alerter *alert.Alerter, threshold int, window time.Duration) gin.HandlerFunc {
+	var (
+		mu      sync.Mutex
+		count   int
+		resetAt time.Time
+	)
+	return func(c *gin.Context) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				alerter.Alert(alert.KindPanic, "%s %s panicked: %v", c.Request.Method, c.FullPath(), rec)
+				panic(rec)
+			}
+		}()
+		c.Next()
+
+		if threshold <= 0 || c.Writer.Status() < http.StatusInternalServerError {
+			return
+		}
+		now := time.Now()
+		mu.Lock()
+		if !now.Before(resetAt) {
+			count, resetAt = 0, now.Add(window)
+		}
+		count++
+		burst := count == threshold
+		mu.Unlock()
+		if burst {
+			alerter.Alert(alert.KindServerErrors, "%d responses with a 5xx status within %s, the last one to %s %s",
+				threshold, window, c.Request.Method, c.Request.URL.Path)
+		}
+	}
+}
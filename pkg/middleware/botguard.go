@@ -0,0 +1,145 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rkgcloud/crud/pkg/api/render"
+	"github.com/rkgcloud/crud/pkg/captcha"
+	"github.com/rkgcloud/crud/pkg/config"
+	"github.com/rkgcloud/crud/pkg/problem"
+	"github.com/rkgcloud/crud/pkg/verification"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Headers carrying the form token and the captcha widget response
+const (
+	FormTokenHeader = "X-Form-Token"
+	CaptchaHeader   = "X-Captcha-Response"
+)
+
+// botRejected counts the submissions refused by a BotGuard by check, served at /debug/vars
+var botRejected = expvar.NewMap("bot_rejections_total")
+
+// BotGuard rejects automated submissions of public forms. A submission is
+// rejected when it fills the honeypot field, comes sooner than MinSubmitTime
+// after the form token it carries was issued, or lacks a captcha response
+// the provider accepts. Each check is only made when configured, and requests
+// carrying the admin token are not checked.
+type BotGuard struct {
+	cfg        config.BotGuardConfig
+	adminToken string
+	forms      *verification.Verifier
+	captcha    *captcha.Verifier
+}
+
+// NewBotGuard creates a BotGuard issuing and checking form tokens with forms
+// and captcha responses with captcha, which is nil when captchas are disabled
+func NewBotGuard(cfg config.BotGuardConfig, adminToken string, forms *verification.Verifier, captcha *captcha.Verifier) *BotGuard {
+	return &BotGuard{cfg: cfg, adminToken: adminToken, forms: forms, captcha: captcha}
+}
+
+// formToken is a token a form is rendered with
+type formToken struct {
+	Token string `json:"token"`
+	// NotBefore is when a submission carrying the token is first accepted
+	NotBefore time.Time `json:"not_before"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// FormToken issues a token to send in the X-Form-Token header of the
+// submission of the form being rendered
+func (g *BotGuard) FormToken(c *gin.Context) error {
+	issued := time.Now()
+	return render.One(c, http.StatusOK, formToken{
+		Token:     g.forms.Token(0, strconv.FormatInt(issued.UnixMilli(), 10)),
+		NotBefore: issued.Add(g.cfg.MinSubmitTime).UTC(),
+		ExpiresAt: issued.Add(g.forms.TTL()).UTC(),
+	})
+}
+
+// Handler returns the gin middleware guarding the routes it is added to
+func (g *BotGuard) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if isAdmin(c, g.adminToken) {
+			c.Next()
+			return
+		}
+		if g.cfg.HoneypotField != "" && g.honeypotFilled(c) {
+			botRejected.Add("honeypot", 1)
+			problem.Abort(c, http.StatusBadRequest, "Submission rejected")
+			return
+		}
+		if g.cfg.MinSubmitTime > 0 && !g.checkFormToken(c) {
+			return
+		}
+		if g.captcha != nil && !g.checkCaptcha(c) {
+			return
+		}
+		c.Next()
+	}
+}
+
+// honeypotFilled reports whether the JSON body sets the honeypot field to
+// anything but an empty string or null. The body is restored for the handler.
+func (g *BotGuard) honeypotFilled(c *gin.Context) bool {
+	body, err := c.GetRawData()
+	if err != nil {
+		return false
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(body, &fields) != nil {
+		return false
+	}
+	value, ok := fields[g.cfg.HoneypotField]
+	return ok && string(value) != `""` && string(value) != "null"
+}
+
+// checkFormToken rejects the request unless its form token is valid and old
+// enough, and reports whether it passed
+func (g *BotGuard) checkFormToken(c *gin.Context) bool {
+	_, subject, err := g.forms.Verify(c.GetHeader(FormTokenHeader))
+	if errors.Is(err, verification.ErrExpiredToken) {
+		botRejected.Add("form_token", 1)
+		problem.Abort(c, http.StatusBadRequest, "The form token expired, reload the form and submit it again")
+		return false
+	}
+	issued, parseErr := strconv.ParseInt(subject, 10, 64)
+	if err != nil || parseErr != nil {
+		botRejected.Add("form_token", 1)
+		problem.Abort(c, http.StatusBadRequest, FormTokenHeader+" is missing or invalid; get one from GET /forms/token")
+		return false
+	}
+	if time.Since(time.UnixMilli(issued)) < g.cfg.MinSubmitTime {
+		botRejected.Add("too_fast", 1)
+		problem.Abort(c, http.StatusBadRequest, "The form was submitted too quickly, wait a moment and submit it again")
+		return false
+	}
+	return true
+}
+
+// checkCaptcha rejects the request unless the provider accepts its captcha
+// response, and reports whether it passed
+func (g *BotGuard) checkCaptcha(c *gin.Context) bool {
+	err := g.captcha.Verify(c.Request.Context(), c.GetHeader(CaptchaHeader), c.ClientIP())
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, captcha.ErrRejected):
+		botRejected.Add("captcha", 1)
+		problem.Abort(c, http.StatusForbidden, "Captcha verification failed")
+	default:
+		log.Println("Captcha verification failed:", err)
+		problem.Abort(c, http.StatusServiceUnavailable, "Could not verify the captcha, try again later")
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rkgcloud/crud/pkg/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	corsAllowMethods  = "GET, POST, PUT, DELETE, OPTIONS"
+	corsAllowHeaders  = "Authorization, Content-Type, X-Request-ID, " + FormTokenHeader + ", " + CaptchaHeader
+	corsExposeHeaders = "X-Request-ID, RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, RateLimit-Warning, Retry-After"
+	corsMaxAge        = 12 * time.Hour
+)
+
+// CORS answers cross-origin requests from the configured allowed origins
+type CORS struct {
+	mu      sync.RWMutex
+	origins []config.Origin
+}
+
+// NewCORS creates a CORS middleware allowing origins
+func NewCORS(origins []config.Origin) *CORS {
+	return &CORS{origins: origins}
+}
+
+// OnConfigReload applies the reloaded allowed origins
+func (m *CORS) OnConfigReload(cfg *config.Config) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.origins = cfg.AllowedOrigins
+}
+
+// Handler returns the gin middleware
+func (m *CORS) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+		c.Header("Vary", "Origin")
+
+		allowed, ok := m.match(origin)
+		if !ok {
+			if c.Request.Method == http.MethodOptions {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
+		c.Header("Access-Control-Allow-Origin", allowed)
+		c.Header("Access-Control-Expose-Headers", corsExposeHeaders)
+
+		if c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != "" {
+			c.Header("Access-Control-Allow-Methods", corsAllowMethods)
+			c.Header("Access-Control-Allow-Headers", corsAllowHeaders)
+			c.Header("Access-Control-Max-Age", strconv.Itoa(int(corsMaxAge.Seconds())))
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+		c.Next()
+	}
+}
+
+// match returns the Access-Control-Allow-Origin value for origin, if it is allowed
+func (m *CORS) match(origin string) (string, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, o := range m.origins {
+		if o.Matches(origin) {
+			if o.Any() {
+				return "*", true
+			}
+			return origin, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"expvar"
+	"math"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/rkgcloud/crud/pkg/config"
+	"github.com/rkgcloud/crud/pkg/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Load shedding counters served at /debug/vars
+var (
+	requestsInFlight = expvar.NewInt("requests_in_flight")
+	requestsQueued   = expvar.NewInt("requests_queued")
+	requestsShed     = expvar.NewInt("requests_shed_total")
+	// queueWait is the total time requests waited for a slot, in milliseconds
+	queueWait = expvar.NewInt("request_queue_wait_ms_total")
+)
+
+// Shedder bounds the requests handled concurrently. Requests beyond the limit
+// wait in a bounded queue for a slot and are answered with 503 and a
+// Retry-After header when the queue is full or their wait times out.
+type Shedder struct {
+	// slots holds a token for every request being handled; nil disables shedding
+	slots    chan struct{}
+	queued   atomic.Int64
+	maxQueue int64
+	timeout  time.Duration
+}
+
+// NewShedder creates a Shedder handling up to cfg.MaxInFlight requests at
+// once; 0 disables it
+func NewShedder(cfg config.LoadShedConfig) *Shedder {
+	s := &Shedder{maxQueue: int64(cfg.MaxQueue), timeout: cfg.QueueTimeout}
+	if cfg.MaxInFlight > 0 {
+		s.slots = make(chan struct{}, cfg.MaxInFlight)
+	}
+	return s
+}
+
+// Handler returns the gin middleware
+func (s *Shedder) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.slots == nil {
+			c.Next()
+			return
+		}
+		select {
+		case s.slots <- struct{}{}:
+		default:
+			if !s.wait(c) {
+				return
+			}
+		}
+		requestsInFlight.Add(1)
+		defer func() {
+			<-s.slots
+			requestsInFlight.Add(-1)
+		}()
+		c.Next()
+	}
+}
+
+// wait queues the request until a slot frees up and reports whether it got
+// one. Requests that do not are aborted.
+func (s *Shedder) wait(c *gin.Context) bool {
+	if s.queued.Add(1) > s.maxQueue {
+		s.queued.Add(-1)
+		s.shed(c)
+		return false
+	}
+	requestsQueued.Add(1)
+	start := time.Now()
+	defer func() {
+		s.queued.Add(-1)
+		requestsQueued.Add(-1)
+		queueWait.Add(time.Since(start).Milliseconds())
+	}()
+
+	timer := time.NewTimer(s.timeout)
+	defer timer.Stop()
+	select {
+	case s.slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		s.shed(c)
+	case <-c.Request.Context().Done():
+		// The client is gone; there is no one to answer
+		c.Abort()
+	}
+	return false
+}
+
+// shed refuses the request, asking the client to retry once the queue had time to drain
+func (s *Shedder) shed(c *gin.Context) {
+	requestsShed.Add(1)
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(max(s.timeout, time.Second).Seconds()))))
+	problem.Abort(c, http.StatusServiceUnavailable, "Service overloaded, retry later")
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"expvar"
+	"strconv"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Request counters served at /debug/vars. Responses are counted by route, as
+// "METHOD /path" with the path as registered, and status class, such as
+// {"GET /users/:id": {"2xx": 120, "4xx": 3}}, so error rates can be alerted on
+// per route. Requests matching no route are counted under "unmatched".
+var httpResponses = expvar.NewMap("http_responses_total")
+
+// Metrics counts the responses of every route by status class. It must come before gin.Recovery, so panics are counted as the
+// 500 responses Recovery answers them with.
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		route := "unmatched"
+		if c.FullPath() != "" {
+			route = c.Request.Method + " " + c.FullPath()
+		}
+		class := strconv.Itoa(c.Writer.Status()/100) + "xx"
+		routeCounts(route).Add(class, 1)
+	}
+}
+
+// newRoute serializes the creation of the counts of a route
+var newRoute sync.Mutex
+
+// routeCounts returns the response counts of route, creating them on its first response
+func routeCounts(route string) *expvar.Map {
+	if counts, ok := httpResponses.Get(route).(*expvar.Map); ok {
+		return counts
+	}
+	newRoute.Lock()
+	defer newRoute.Unlock()
+	if counts, ok := httpResponses.Get(route).(*expvar.Map); ok {
+		return counts
+	}
+	counts := new(expvar.Map)
+	httpResponses.Set(route, counts)
+	return counts
+}
@@ -0,0 +1,150 @@
+package middleware
+
+import (
+	"expvar"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rkgcloud/crud/pkg/api/render"
+	"github.com/rkgcloud/crud/pkg/config"
+	"github.com/rkgcloud/crud/pkg/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rateLimited counts the requests refused by a RateLimiter, served at /debug/vars
+var rateLimited = expvar.NewInt("rate_limited_total")
+
+// quotaKey is the key of the quota of the request in the gin context
+const quotaKey = "rate_limit_quota"
+
+// RateLimiter limits the requests of every client IP within fixed windows and
+// reports the quota in the RateLimit-Limit, RateLimit-Remaining and
+// RateLimit-Reset headers of the IETF httpapi-ratelimit-headers draft.
+// Once a client has used the warning share of its quota, responses also carry
+// a RateLimit-Warning header, so it can slow down before being refused.
+// Requests from exempt networks or health checkers and requests carrying the
+// admin token are not limited.
+type RateLimiter struct {
+	mu         sync.Mutex
+	limit      int
+	window     time.Duration
+	exemptNets []*net.IPNet
+	agents     []string
+	warn       int
+	adminToken string
+	store      Store
+}
+
+// NewRateLimiter creates a RateLimiter allowing cfg.Requests requests per
+// cfg.Window, counted in store; 0 requests disables it. A nil store counts in
+// memory. Requests carrying adminToken are exempt.
+func NewRateLimiter(cfg config.RateLimitConfig, adminToken string, store Store) *RateLimiter {
+	if store == nil {
+		store = NewMemoryStore()
+	}
+	l := &RateLimiter{adminToken: adminToken, store: store}
+	l.apply(cfg)
+	return l
+}
+
+// OnConfigReload applies the reloaded limit and exemptions; running windows
+// keep their reset time
+func (l *RateLimiter) OnConfigReload(cfg *config.Config) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.apply(cfg.RateLimit)
+}
+
+func (l *RateLimiter) apply(cfg config.RateLimitConfig) {
+	l.limit, l.window = cfg.Requests, cfg.Window
+	l.exemptNets, l.agents = cfg.ExemptNets, cfg.ExemptUserAgents
+	l.warn = cfg.WarnPercent
+}
+
+// exempt reports whether the request of the client at address client is let
+// through without being counted
+func (l *RateLimiter) exempt(c *gin.Context, client string, nets []*net.IPNet, agents []string) bool {
+	if inNets(client, nets) || isAdmin(c, l.adminToken) {
+		return true
+	}
+	userAgent := c.Request.UserAgent()
+	for _, agent := range agents {
+		if strings.Contains(userAgent, agent) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handler returns the gin middleware. Requests are let through unlimited
+// while the store fails, so an unavailable backend does not take the service down.
+func (l *RateLimiter) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		l.mu.Lock()
+		limit, window, nets, agents, warn := l.limit, l.window, l.exemptNets, l.agents, l.warn
+		l.mu.Unlock()
+		// The client address is only read from X-Forwarded-For when the
+		// engine trusts the peer as a proxy, so it cannot be spoofed
+		client := c.ClientIP()
+		if limit == 0 || l.exempt(c, client, nets, agents) {
+			c.Next()
+			return
+		}
+		count, reset, err := l.store.Increment(c.Request.Context(), client, window)
+		if err != nil {
+			log.Println("Rate limit store failed, not limiting:", err)
+			c.Next()
+			return
+		}
+
+		resetSeconds := strconv.Itoa(int(math.Ceil(time.Until(reset).Seconds())))
+		c.Header("RateLimit-Limit", strconv.Itoa(limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(max(limit-count, 0)))
+		c.Header("RateLimit-Reset", resetSeconds)
+		c.Set(quotaKey, quota{
+			Limited: true, Limit: limit, Used: count, Remaining: max(limit-count, 0),
+			Window: window.String(), ResetsAt: reset.UTC(),
+		})
+		if warn > 0 && count <= limit && count*100 >= limit*warn {
+			c.Header("RateLimit-Warning", fmt.Sprintf("%d of %d requests used, the quota resets in %ss", count, limit, resetSeconds))
+		}
+		if count > limit {
+			rateLimited.Add(1)
+			c.Header("Retry-After", resetSeconds)
+			problem.Abort(c, http.StatusTooManyRequests, "Rate limit exceeded, retry after "+resetSeconds+"s")
+			return
+		}
+		c.Next()
+	}
+}
+
+// quota is the rate limit quota of a client in the current window
+type quota struct {
+	Limited   bool      `json:"limited"`
+	Limit     int       `json:"limit"`
+	Used      int       `json:"used"`
+	Remaining int       `json:"remaining"`
+	Window    string    `json:"window"`
+	ResetsAt  time.Time `json:"resets_at"`
+}
+
+// Limits returns the quota of the client in the current window, including
+// the request asking for it, so integrators can throttle themselves before
+// they are refused. It must be routed after Handler.
+func (l *RateLimiter) Limits(c *gin.Context) error {
+	q, ok := c.Get(quotaKey)
+	if !ok {
+		// Exempt clients, and every client while the limit is off or the
+		// store fails, are not limited
+		return render.One(c, http.StatusOK, gin.H{"limited": false})
+	}
+	return render.One(c, http.StatusOK, q)
+}
@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Store counts the requests of every client for a RateLimiter. Deployments
+// can share counts between instances by implementing it on top of any
+// backend with an atomic increment and expiry, such as memcached or DynamoDB.
+type Store interface {
+	// Increment counts a request of key in its current window, starting a
+	// window of the given length when none is running, and returns the
+	// requests counted in the window, including this one, and when it resets
+	Increment(ctx context.Context, key string, window time.Duration) (count int, reset time.Time, err error)
+}
+
+// MemoryStore keeps the counts in process, so every instance limits on its own
+type MemoryStore struct {
+	mu      sync.Mutex
+	windows map[string]*rateWindow
+	// sweepAt is when expired windows are next dropped
+	sweepAt time.Time
+}
+
+// rateWindow counts the requests of one client in the current window
+type rateWindow struct {
+	count int
+	reset time.Time
+}
+
+// NewMemoryStore creates an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{windows: map[string]*rateWindow{}}
+}
+
+// Increment implements Store
+func (s *MemoryStore) Increment(ctx context.Context, key string, window time.Duration) (int, time.Time, error) {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now.After(s.sweepAt) {
+		for k, w := range s.windows {
+			if !now.Before(w.reset) {
+				delete(s.windows, k)
+			}
+		}
+		s.sweepAt = now.Add(window)
+	}
+
+	w, found := s.windows[key]
+	if !found || !now.Before(w.reset) {
+		w = &rateWindow{reset: now.Add(window)}
+		s.windows[key] = w
+	}
+	w.count++
+	return w.count, w.reset, nil
+}
+
+// redisIncrement counts a request and starts the window on the first one. The
+// expiry is also set on keys without one, so a key never outlives its window.
+var redisIncrement = redis.NewScript(`
+local count = redis.call("INCR", KEYS[1])
+local ttl = redis.call("PTTL", KEYS[1])
+if ttl < 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+	ttl = tonumber(ARGV[1])
+end
+return {count, ttl}
+`)
+
+// RedisStore keeps the counts in Redis, so every instance shares the same limit
+type RedisStore struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewRedisStore creates a RedisStore keeping counts under keys prefixed with
+// "ratelimit:"
+func NewRedisStore(client redis.UniversalClient) *RedisStore {
+	return &RedisStore{client: client, prefix: "ratelimit:"}
+}
+
+// Increment implements Store
+func (s *RedisStore) Increment(ctx context.Context, key string, window time.Duration) (int, time.Time, error) {
+	now := time.Now()
+	res, err := redisIncrement.Run(ctx, s.client, []string{s.prefix + key}, window.Milliseconds()).Int64Slice()
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	return int(res[0]), now.Add(time.Duration(res[1]) * time.Millisecond), nil
+}
@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rkgcloud/crud/pkg/config"
+	"github.com/rkgcloud/crud/pkg/problem"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newLimitedEngine serves /ping behind a limiter allowing one request per
+// window, trusting X-Forwarded-For from proxies only
+func newLimitedEngine(t *testing.T, proxies []string, exempt ...string) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	var nets []*net.IPNet
+	for _, cidr := range exempt {
+		_, n, err := net.ParseCIDR(cidr)
+		require.NoError(t, err)
+		nets = append(nets, n)
+	}
+	limiter := NewRateLimiter(config.RateLimitConfig{Requests: 1, Window: time.Minute, ExemptNets: nets}, "", nil)
+	r := gin.New()
+	require.NoError(t, r.SetTrustedProxies(proxies))
+	r.Use(problem.Handler(), limiter.Handler())
+	r.GET("/ping", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	return r
+}
+
+func ping(r *gin.Engine, remoteAddr, forwardedFor string) int {
+	req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+	req.RemoteAddr = remoteAddr
+	if forwardedFor != "" {
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestRateLimiterKeysOnClientAddress(t *testing.T) {
+	tests := []struct {
+		name     string
+		proxies  []string
+		requests [][2]string // remote address, X-Forwarded-For
+		want     []int
+	}{
+		{
+			name:     "rotating X-Forwarded-For from an untrusted peer shares its quota",
+			requests: [][2]string{{"203.0.113.7:1000", "198.51.100.1"}, {"203.0.113.7:1001", "198.51.100.2"}},
+			want:     []int{http.StatusNoContent, http.StatusTooManyRequests},
+		},
+		{
+			name:     "an untrusted peer cannot spend another client's quota",
+			requests: [][2]string{{"203.0.113.7:1000", "198.51.100.1"}, {"198.51.100.1:1000", ""}},
+			want:     []int{http.StatusNoContent, http.StatusNoContent},
+		},
+		{
+			name:     "clients behind a trusted proxy are counted apart",
+			proxies:  []string{"10.0.0.0/8"},
+			requests: [][2]string{{"10.0.0.2:1000", "198.51.100.1"}, {"10.0.0.2:1001", "198.51.100.2"}, {"10.0.0.2:1002", "198.51.100.1"}},
+			want:     []int{http.StatusNoContent, http.StatusNoContent, http.StatusTooManyRequests},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newLimitedEngine(t, tt.proxies)
+			for i, req := range tt.requests {
+				assert.Equal(t, tt.want[i], ping(r, req[0], req[1]), "request %d", i+1)
+			}
+		})
+	}
+}
+
+func TestRateLimiterExemptionCannotBeSpoofed(t *testing.T) {
+	r := newLimitedEngine(t, nil, "10.0.0.0/8")
+	assert.Equal(t, http.StatusNoContent, ping(r, "203.0.113.7:1000", "10.0.0.1"))
+	assert.Equal(t, http.StatusTooManyRequests, ping(r, "203.0.113.7:1000", "10.0.0.1"))
+
+	// Internal clients stay exempt
+	for range 3 {
+		assert.Equal(t, http.StatusNoContent, ping(r, "10.0.0.1:1000", ""))
+	}
+}
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/rkgcloud/crud/pkg/config"
+	"github.com/rkgcloud/crud/pkg/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Timeout bounds the context of every request by cfg.Default, or by the
+// override of its route in cfg.Routes. The handler runs on the request
+// goroutine, so no work outlives the request and nothing writes to the
+// response after it: database statements and outbound calls made with the
+// request context fail once it expires, and a handler that returns without
+// responding is answered with 503.
+func Timeout(cfg config.RequestTimeoutConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		timeout, ok := cfg.Routes[c.Request.Method+" "+c.FullPath()]
+		if !ok {
+			timeout = cfg.Default
+		}
+		if timeout == 0 {
+			c.Next()
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && !c.Writer.Written() && !c.IsAborted() {
+			problem.Abort(c, http.StatusServiceUnavailable, "Request timed out after "+timeout.String())
+		}
+	}
+}
@@ -1,11 +1,125 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Roles that can be assigned to a user
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
 
 // User represents a user in the database
 type User struct {
 	gorm.Model
-	Name  string `json:"name" binding:"required"`
-	Email string `json:"email" binding:"required,email" gorm:"unique"`
-	Age   int    `json:"age" binding:"required"`
+	Name  string `json:"name"`
+	Email string `json:"email" gorm:"unique"`
+	Age   int    `json:"age"`
+	// Phone is stored in E.164 format; PhoneDisplay is its international formatting
+	Phone        string `json:"phone"`
+	PhoneDisplay string `json:"phone_display"`
+	Verified     bool   `json:"verified" gorm:"not null;default:false"`
+	Role         string `json:"role" gorm:"not null;default:user"`
+	// Timezone is an IANA zone name and Locale a BCP 47 language tag; both are
+	// optional and timestamps shown to the user default to UTC
+	Timezone string `json:"timezone"`
+	Locale   string `json:"locale"`
+	// Metadata holds the attributes integrators attach to the user, under the
+	// keys registered in USER_METADATA_SCHEMA
+	Metadata json.RawMessage `json:"metadata" gorm:"type:jsonb;not null;default:'{}';index:idx_users_metadata,type:gin"`
+}
+
+// Location returns the time zone of the user, or UTC when none is set
+func (u User) Location() *time.Location {
+	if loc, err := time.LoadLocation(u.Timezone); err == nil {
+		return loc
+	}
+	return time.UTC
+}
+
+// Address is a postal address belonging to a user
+type Address struct {
+	gorm.Model
+	UserID     uint   `json:"user_id" gorm:"not null;index"`
+	User       User   `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+	Street     string `json:"street"`
+	City       string `json:"city"`
+	Region     string `json:"region"`
+	PostalCode string `json:"postal_code"`
+	Country    string `json:"country"`
+}
+
+// Types of the records a Tag can label
+const (
+	TaggableUser = "users"
+)
+
+// Tag labels a record of any TaggableType, such as a user tagged "vip"
+type Tag struct {
+	ID           uint      `json:"-" gorm:"primarykey"`
+	CreatedAt    time.Time `json:"created_at"`
+	TaggableType string    `json:"-" gorm:"not null;uniqueIndex:idx_tags_record,priority:1"`
+	TaggableID   uint      `json:"-" gorm:"not null;uniqueIndex:idx_tags_record,priority:2"`
+	Name         string    `json:"name" gorm:"not null;uniqueIndex:idx_tags_record,priority:3;index"`
+}
+
+// Invitation represents a pending invitation for someone to join with a role
+type Invitation struct {
+	gorm.Model
+	Email      string     `json:"email" gorm:"uniqueIndex"`
+	Role       string     `json:"role" gorm:"not null;default:user"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	AcceptedAt *time.Time `json:"accepted_at"`
+}
+
+// OutboxEvent is a domain event written in the same transaction as the change
+// it describes and published asynchronously by the outbox relay
+type OutboxEvent struct {
+	ID          uint            `json:"id" gorm:"primarykey"`
+	Type        string          `json:"type" gorm:"not null"`
+	Aggregate   string          `json:"aggregate" gorm:"not null"`
+	AggregateID uint            `json:"aggregate_id" gorm:"not null"`
+	Payload     json.RawMessage `json:"payload" gorm:"type:jsonb;not null"`
+	CreatedAt   time.Time       `json:"created_at"`
+	PublishedAt *time.Time      `json:"published_at,omitempty" gorm:"index"`
+	Attempts    int             `json:"-" gorm:"not null;default:0"`
+	LastError   string          `json:"-"`
+}
+
+// Export job states
+const (
+	ExportPending   = "pending"
+	ExportRunning   = "running"
+	ExportCompleted = "completed"
+	ExportFailed    = "failed"
+)
+
+// ExportJob is a background export of the users table to a downloadable file
+type ExportJob struct {
+	gorm.Model
+	Format      string     `json:"format" gorm:"not null"`
+	Status      string     `json:"status" gorm:"not null;index"`
+	Rows        int        `json:"rows"`
+	Error       string     `json:"error,omitempty"`
+	FileName    string     `json:"-"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// AuditEntry records an administrative or automated action
+type AuditEntry struct {
+	ID        uint            `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time       `json:"created_at" gorm:"index"`
+	Actor     string          `json:"actor" gorm:"not null"`
+	Action    string          `json:"action" gorm:"not null;index"`
+	Target    string          `json:"target"`
+	Details   json.RawMessage `json:"details" gorm:"type:jsonb"`
+	// DetailsHash is the SHA-256 of the details. Hash chains it and the other
+	// fields with the Hash of the previous entry, PrevHash.
+	DetailsHash string `json:"-" gorm:"not null;default:''"`
+	PrevHash    string `json:"prev_hash" gorm:"not null;default:''"`
+	Hash        string `json:"hash" gorm:"not null;default:''"`
 }
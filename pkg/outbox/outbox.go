@@ -0,0 +1,166 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/rkgcloud/crud/pkg/events"
+	"github.com/rkgcloud/crud/pkg/models"
+
+	"gorm.io/gorm"
+)
+
+// Event types written to the outbox
+const (
+	UserCreated  = "user.created"
+	UserUpdated  = "user.updated"
+	UserDeleted  = "user.deleted"
+	UserVerified = "user.verified"
+	// UserErased tells consumers to erase their copies of the user's personal data
+	UserErased = "user.erased"
+)
+
+// Enqueue records an event about the user in the outbox. tx must be the
+// transaction that performs the change so the event is committed with it.
+func Enqueue(tx *gorm.DB, eventType string, user models.User) error {
+	payload, err := json.Marshal(user)
+	if err != nil {
+		return fmt.Errorf("encoding %s event: %w", eventType, err)
+	}
+	return tx.Create(&models.OutboxEvent{
+		Type:        eventType,
+		Aggregate:   "user",
+		AggregateID: user.ID,
+		Payload:     payload,
+	}).Error
+}
+
+// relayLock is the advisory lock held by the replica publishing a batch
+const relayLock = 0x72656c61
+
+// Relay publishes unpublished outbox events in order
+type Relay struct {
+	db        *gorm.DB
+	publisher events.Publisher
+	interval  time.Duration
+	batchSize int
+	done      chan struct{}
+	onFailure func(event models.OutboxEvent, err error)
+}
+
+// NewRelay creates a Relay polling db every interval for up to batchSize events
+func NewRelay(db *gorm.DB, publisher events.Publisher, interval time.Duration, batchSize int) *Relay {
+	return &Relay{
+		db:        db,
+		publisher: publisher,
+		interval:  interval,
+		batchSize: batchSize,
+		done:      make(chan struct{}),
+	}
+}
+
+// OnFailure registers fn to be called with every event that could not be
+// published, before it is retried. It must be called before Run.
+func (r *Relay) OnFailure(fn func(event models.OutboxEvent, err error)) {
+	r.onFailure = fn
+}
+
+// Run publishes events until ctx is done
+func (r *Relay) Run(ctx context.Context) {
+	defer close(r.done)
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		for {
+			n, err := r.publishBatch(ctx)
+			if err != nil {
+				log.Printf("outbox: %v\n", err)
+			}
+			// Keep draining while full batches are available
+			if err != nil || n < r.batchSize {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Wait blocks until Run has returned or ctx is done
+func (r *Relay) Wait(ctx context.Context) error {
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// publishBatch publishes the oldest unpublished events and returns how many
+// were published. Only the replica holding the relay advisory lock publishes,
+// so events reach the publisher in ID order even with several replicas, and
+// the lock is held on its own connection instead of a transaction left open
+// while publishing. Publishing stops at the first failure to preserve ordering.
+func (r *Relay) publishBatch(ctx context.Context) (int, error) {
+	sqlDB, err := r.db.DB()
+	if err != nil {
+		return 0, err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", relayLock).Scan(&acquired); err != nil {
+		return 0, fmt.Errorf("taking the relay lock: %w", err)
+	}
+	if !acquired {
+		// Another replica is relaying this poll
+		return 0, nil
+	}
+	defer unlock(conn)
+
+	db := r.db.WithContext(ctx)
+	var events []models.OutboxEvent
+	if err := db.Where("published_at IS NULL").Order("id").Limit(r.batchSize).Find(&events).Error; err != nil {
+		return 0, err
+	}
+	published := 0
+	for _, event := range events {
+		if err := r.publisher.Publish(ctx, event); err != nil {
+			event.Attempts++
+			if r.onFailure != nil {
+				r.onFailure(event, err)
+			}
+			return published, db.Model(&event).Updates(map[string]any{
+				"attempts":   gorm.Expr("attempts + 1"),
+				"last_error": err.Error(),
+			}).Error
+		}
+		if err := db.Model(&event).Update("published_at", time.Now()).Error; err != nil {
+			return published, err
+		}
+		published++
+	}
+	return published, nil
+}
+
+// unlock releases the relay lock. When that fails, the connection is closed
+// rather than returned to the pool, which makes the server drop the lock.
+func unlock(conn *sql.Conn) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", relayLock); err != nil {
+		log.Printf("outbox: could not release the relay lock: %v\n", err)
+		_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+	}
+}
@@ -0,0 +1,83 @@
+package problem
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/rkgcloud/crud/pkg/requestid"
+	"github.com/rkgcloud/crud/pkg/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ContentType is the media type of problem details (RFC 7807)
+const ContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details object
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// RequestID correlates the response with the server logs
+	RequestID string `json:"request_id,omitempty"`
+	// Fields lists the invalid fields of a rejected request body
+	Fields []validation.FieldError `json:"fields,omitempty"`
+}
+
+// New creates a problem of the generic about:blank type
+func New(status int, detail string) *Problem {
+	return &Problem{Type: "about:blank", Title: http.StatusText(status), Status: status, Detail: detail}
+}
+
+// Error returns the detail of the problem
+func (p *Problem) Error() string {
+	return p.Detail
+}
+
+// Abort stops the request and responds with a problem of status
+func Abort(c *gin.Context, status int, detail string) {
+	_ = c.Error(New(status, detail))
+	c.Abort()
+}
+
+// Invalid stops the request with a 400 problem listing the invalid fields of err
+func Invalid(c *gin.Context, err error) {
+	_ = c.Error(Validation(err))
+	c.Abort()
+}
+
+// Validation creates the 400 problem listing the invalid fields of err
+func Validation(err error) *Problem {
+	p := New(http.StatusBadRequest, err.Error())
+	if fields := validation.Fields(err); fields != nil {
+		p.Detail = "The request has invalid fields"
+		p.Fields = fields
+	}
+	return p
+}
+
+// Handler renders the last error of a request as problem details unless a
+// response was already written. Errors other than a Problem are logged and
+// reported as 500 without their details.
+func Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		err := c.Errors.Last()
+		if err == nil || c.Writer.Written() {
+			return
+		}
+		var p *Problem
+		if !errors.As(err.Err, &p) {
+			log.Printf("Error handling %s %s: %v\n", c.Request.Method, c.Request.URL.Path, err.Err)
+			p = New(http.StatusInternalServerError, "")
+		}
+		response := *p
+		response.Instance = c.Request.URL.Path
+		response.RequestID = requestid.Get(c)
+		c.Header("Content-Type", ContentType)
+		c.JSON(response.Status, response)
+	}
+}
@@ -0,0 +1,18 @@
+// Package repository holds the data-access interfaces of the service and
+// their gorm implementations. Mocks of the interfaces are generated under
+// pkg/testing/mocks with `make mocks`.
+package repository
+
+import "gorm.io/gorm"
+
+// Paginate loads the limit records matched by query after offset into dest,
+// ordered by ID, and returns the total number of matching records
+func Paginate[T any](query *gorm.DB, offset, limit int, dest *[]T) (int64, error) {
+	query = query.Session(&gorm.Session{})
+	var total int64
+	if err := query.Model(dest).Count(&total).Error; err != nil {
+		return 0, err
+	}
+	err := query.Order("id").Offset(offset).Limit(limit).Find(dest).Error
+	return total, err
+}
@@ -0,0 +1,222 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/rkgcloud/crud/pkg/audit"
+	"github.com/rkgcloud/crud/pkg/filter"
+	"github.com/rkgcloud/crud/pkg/models"
+	"github.com/rkgcloud/crud/pkg/outbox"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Users stores users. Every change is committed together with its outbox
+// event. Errors are gorm errors, to be classified with dberr.Translate.
+type Users interface {
+	// Get loads the user with id
+	Get(ctx context.Context, id uint) (models.User, error)
+	// List loads the limit users matching conds after offset and counts all matches
+	List(ctx context.Context, conds []filter.Condition, offset, limit int) ([]models.User, int64, error)
+	// ListDeleted loads the limit soft-deleted users after offset and counts them all
+	ListDeleted(ctx context.Context, offset, limit int) ([]models.User, int64, error)
+	// Suggest loads up to limit users whose name or email starts with prefix,
+	// ignoring case, ordered by name
+	Suggest(ctx context.Context, prefix string, limit int) ([]models.User, error)
+	// Create inserts user, setting its ID and timestamps
+	Create(ctx context.Context, user *models.User) error
+	// Update saves every field of user
+	Update(ctx context.Context, user *models.User) error
+	// Delete soft-deletes user
+	Delete(ctx context.Context, user *models.User) error
+	// Tags loads the tags of user ordered by name
+	Tags(ctx context.Context, user models.User) ([]string, error)
+	// AddTag tags user with tag; adding a tag it already has changes nothing
+	AddTag(ctx context.Context, user models.User, tag string) error
+	// RemoveTag removes tag from user, failing with gorm.ErrRecordNotFound
+	// when user does not have it
+	RemoveTag(ctx context.Context, user models.User, tag string) error
+	// MarkVerified records that user proved ownership of their email address
+	MarkVerified(ctx context.Context, user *models.User) error
+	// RecordUndeliverable audits that the email domain of user accepts no email
+	RecordUndeliverable(ctx context.Context, user models.User, domain string) error
+	// Erase anonymizes the personal data of user wherever it is stored, keeping
+	// the user row so records referring to it stay valid
+	Erase(ctx context.Context, user *models.User) (ErasureReport, error)
+}
+
+// ErasureReport describes what Erase changed
+type ErasureReport struct {
+	UserID               uint      `json:"user_id"`
+	ErasedAt             time.Time `json:"erased_at"`
+	AddressesDeleted     int64     `json:"addresses_deleted"`
+	InvitationsDeleted   int64     `json:"invitations_deleted"`
+	AuditEntriesScrubbed int64     `json:"audit_entries_scrubbed"`
+	EventsScrubbed       int64     `json:"events_scrubbed"`
+}
+
+// NewUsers creates the Users repository stored in db
+func NewUsers(db *gorm.DB) Users {
+	return &gormUsers{db: db}
+}
+
+// gormUsers implements Users with gorm
+type gormUsers struct {
+	db *gorm.DB
+}
+
+func (r *gormUsers) Get(ctx context.Context, id uint) (models.User, error) {
+	var user models.User
+	err := r.db.WithContext(ctx).First(&user, id).Error
+	return user, err
+}
+
+func (r *gormUsers) List(ctx context.Context, conds []filter.Condition, offset, limit int) ([]models.User, int64, error) {
+	var users []models.User
+	total, err := Paginate(r.db.WithContext(ctx).Scopes(filter.Scope(conds)), offset, limit, &users)
+	return users, total, err
+}
+
+func (r *gormUsers) ListDeleted(ctx context.Context, offset, limit int) ([]models.User, int64, error) {
+	var users []models.User
+	total, err := Paginate(r.db.WithContext(ctx).Unscoped().Where("deleted_at IS NOT NULL"), offset, limit, &users)
+	return users, total, err
+}
+
+func (r *gormUsers) Suggest(ctx context.Context, prefix string, limit int) ([]models.User, error) {
+	var users []models.User
+	pattern := filter.EscapeLike(prefix) + "%"
+	err := r.db.WithContext(ctx).
+		Where("name ILIKE ? OR email ILIKE ?", pattern, pattern).
+		Order("name").Order("id").Limit(limit).
+		Find(&users).Error
+	return users, err
+}
+
+func (r *gormUsers) Create(ctx context.Context, user *models.User) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		return outbox.Enqueue(tx, outbox.UserCreated, *user)
+	})
+}
+
+func (r *gormUsers) Update(ctx context.Context, user *models.User) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(user).Error; err != nil {
+			return err
+		}
+		return outbox.Enqueue(tx, outbox.UserUpdated, *user)
+	})
+}
+
+func (r *gormUsers) Delete(ctx context.Context, user *models.User) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(user).Error; err != nil {
+			return err
+		}
+		return outbox.Enqueue(tx, outbox.UserDeleted, *user)
+	})
+}
+
+func (r *gormUsers) Tags(ctx context.Context, user models.User) ([]string, error) {
+	tags := []string{}
+	err := r.db.WithContext(ctx).Model(&models.Tag{}).
+		Where("taggable_type = ? AND taggable_id = ?", models.TaggableUser, user.ID).
+		Order("name").Pluck("name", &tags).Error
+	return tags, err
+}
+
+func (r *gormUsers) AddTag(ctx context.Context, user models.User, tag string) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.Tag{TaggableType: models.TaggableUser, TaggableID: user.ID, Name: tag}).Error
+}
+
+func (r *gormUsers) RemoveTag(ctx context.Context, user models.User, tag string) error {
+	result := r.db.WithContext(ctx).
+		Where("taggable_type = ? AND taggable_id = ? AND name = ?", models.TaggableUser, user.ID, tag).
+		Delete(&models.Tag{})
+	if result.Error == nil && result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return result.Error
+}
+
+// Tagged is a filter condition matching the records of taggableType tagged
+// with tag, to append to the conditions of a listing
+func Tagged(taggableType, tag string) filter.Condition {
+	return filter.Condition{
+		Column: "id",
+		Op:     "IN",
+		Value:  gorm.Expr("(SELECT taggable_id FROM tags WHERE taggable_type = ? AND name = ?)", taggableType, tag),
+	}
+}
+
+func (r *gormUsers) MarkVerified(ctx context.Context, user *models.User) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Update("verified", true).Error; err != nil {
+			return err
+		}
+		return outbox.Enqueue(tx, outbox.UserVerified, *user)
+	})
+}
+
+func (r *gormUsers) RecordUndeliverable(ctx context.Context, user models.User, domain string) error {
+	details := map[string]any{"email": user.Email, "domain": domain}
+	return audit.Record(r.db.WithContext(ctx), audit.ActorSystem, "user.email_undeliverable", fmt.Sprintf("users/%d", user.ID), details)
+}
+
+func (r *gormUsers) Erase(ctx context.Context, user *models.User) (ErasureReport, error) {
+	report := ErasureReport{UserID: user.ID, ErasedAt: time.Now()}
+	target := fmt.Sprintf("users/%d", user.ID)
+	email := user.Email
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		user.Name = "Erased user"
+		user.Email = fmt.Sprintf("erased-%d@erased.invalid", user.ID)
+		user.Phone, user.PhoneDisplay = "", ""
+		user.Metadata = json.RawMessage("{}")
+		user.Verified = false
+		if err := tx.Save(user).Error; err != nil {
+			return err
+		}
+
+		result := tx.Unscoped().Where("user_id = ?", user.ID).Delete(&models.Address{})
+		if result.Error != nil {
+			return result.Error
+		}
+		report.AddressesDeleted = result.RowsAffected
+		result = tx.Unscoped().Where("email = ?", email).Delete(&models.Invitation{})
+		if result.Error != nil {
+			return result.Error
+		}
+		report.InvitationsDeleted = result.RowsAffected
+
+		// Events keep their shape, so unpublished ones can still be relayed
+		anonymized := gorm.Expr("payload || jsonb_build_object('name', ?::text, 'email', ?::text, 'phone', '', 'phone_display', '', 'metadata', '{}'::jsonb)", user.Name, user.Email)
+		result = tx.Model(&models.OutboxEvent{}).
+			Where("aggregate = ? AND aggregate_id = ?", "user", user.ID).
+			Update("payload", anonymized)
+		if result.Error != nil {
+			return result.Error
+		}
+		report.EventsScrubbed = result.RowsAffected
+		result = tx.Model(&models.AuditEntry{}).
+			Where("target = ? AND jsonb_typeof(details) = 'object'", target).
+			Update("details", gorm.Expr("details - 'name' - 'email' - 'phone' - 'phone_display'"))
+		if result.Error != nil {
+			return result.Error
+		}
+		report.AuditEntriesScrubbed = result.RowsAffected
+
+		if err := outbox.Enqueue(tx, outbox.UserErased, *user); err != nil {
+			return err
+		}
+		return audit.Record(tx, audit.ActorAdmin, "user.erased", target, report)
+	})
+	return report, err
+}
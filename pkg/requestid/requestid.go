@@ -0,0 +1,76 @@
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Header carries the request ID in requests and responses
+const Header = "X-Request-ID"
+
+// contextKey stores the request ID in the gin context
+const contextKey = "request_id"
+
+// ctxKey stores the IDs in the request context, for code that only has a context.Context
+type ctxKey struct{}
+
+// ids are the identifiers of a request
+type ids struct {
+	request string
+	trace   string
+}
+
+var (
+	// valid matches request IDs accepted from clients and proxies
+	valid = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+	// traceparent matches a W3C Trace Context header, capturing the trace ID
+	traceparent = regexp.MustCompile(`^[0-9a-f]{2}-([0-9a-f]{32})-[0-9a-f]{16}-[0-9a-f]{2}$`)
+)
+
+// Middleware assigns every request an ID, reusing a well-formed X-Request-ID
+// from the client or proxy, and echoes it in the response. The trace ID of a
+// W3C traceparent header is kept alongside it.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(Header)
+		if !valid.MatchString(id) {
+			id = generate()
+		}
+		var trace string
+		if m := traceparent.FindStringSubmatch(c.GetHeader("traceparent")); m != nil {
+			trace = m[1]
+		}
+		c.Set(contextKey, id)
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), ctxKey{}, ids{request: id, trace: trace}))
+		c.Header(Header, id)
+		c.Next()
+	}
+}
+
+// Get returns the ID of the request, or "" outside Middleware
+func Get(c *gin.Context) string {
+	return c.GetString(contextKey)
+}
+
+// FromContext returns the request ID carried by the context of a request
+func FromContext(ctx context.Context) string {
+	v, _ := ctx.Value(ctxKey{}).(ids)
+	return v.request
+}
+
+// TraceFromContext returns the W3C trace ID carried by the context of a
+// request, or "" when the request was not traced
+func TraceFromContext(ctx context.Context) string {
+	v, _ := ctx.Value(ctxKey{}).(ids)
+	return v.trace
+}
+
+func generate() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,126 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// Elector decides whether this replica runs the scheduled tasks, so tasks run
+// once across replicas rather than once per replica
+type Elector interface {
+	IsLeader() bool
+}
+
+// leaderLock is the advisory lock held by the leader
+const leaderLock = 0x6c656164
+
+// PostgresElector elects as leader the replica holding a session advisory
+// lock on the shared database. The lock is held on a dedicated connection,
+// so it is released by the server as soon as the leader's connection is lost,
+// and another replica takes over on its next attempt.
+type PostgresElector struct {
+	db       *sql.DB
+	interval time.Duration
+	conn     *sql.Conn
+	leader   atomic.Bool
+	done     chan struct{}
+}
+
+// NewPostgresElector creates a PostgresElector trying to become or stay leader every interval
+func NewPostgresElector(db *sql.DB, interval time.Duration) *PostgresElector {
+	return &PostgresElector{db: db, interval: interval, done: make(chan struct{})}
+}
+
+// IsLeader implements Elector
+func (e *PostgresElector) IsLeader() bool {
+	return e.leader.Load()
+}
+
+// Run campaigns for leadership until ctx is done, then steps down
+func (e *PostgresElector) Run(ctx context.Context) {
+	defer close(e.done)
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	for {
+		e.campaign(ctx)
+		select {
+		case <-ctx.Done():
+			e.stepDown()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Wait blocks until Run has returned or ctx is done
+func (e *PostgresElector) Wait(ctx context.Context) error {
+	select {
+	case <-e.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// campaign checks that the leader still holds its connection, or tries to take the lock
+func (e *PostgresElector) campaign(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, e.interval)
+	defer cancel()
+	if e.conn == nil {
+		conn, err := e.db.Conn(ctx)
+		if err != nil {
+			log.Printf("scheduler: leader election cannot connect: %v\n", err)
+			return
+		}
+		e.conn = conn
+	}
+	if e.leader.Load() {
+		if err := e.conn.PingContext(ctx); err != nil {
+			log.Printf("scheduler: lost leadership with the database connection: %v\n", err)
+			e.release()
+		}
+		return
+	}
+	var acquired bool
+	if err := e.conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", leaderLock).Scan(&acquired); err != nil {
+		log.Printf("scheduler: leader election failed: %v\n", err)
+		e.release()
+		return
+	}
+	if acquired {
+		log.Println("scheduler: elected leader, running scheduled tasks")
+		e.leader.Store(true)
+	}
+}
+
+// stepDown releases the lock so another replica can take over without delay
+func (e *PostgresElector) stepDown() {
+	if e.conn == nil {
+		return
+	}
+	if e.leader.Load() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		if _, err := e.conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", leaderLock); err != nil {
+			log.Printf("scheduler: could not release leadership: %v\n", err)
+		}
+	}
+	e.release()
+}
+
+// release gives up leadership and the connection; closing the connection
+// makes the server drop the lock if it still holds it
+func (e *PostgresElector) release() {
+	e.leader.Store(false)
+	if e.conn != nil {
+		// Raw closes the underlying connection instead of returning it to the
+		// pool, where it would keep the lock
+		_ = e.conn.Raw(func(any) error { return driver.ErrBadConn })
+		e.conn.Close()
+		e.conn = nil
+	}
+}
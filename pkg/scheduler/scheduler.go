@@ -0,0 +1,161 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// TaskFunc is a recurring unit of work. It should return once ctx is done.
+type TaskFunc func(ctx context.Context) error
+
+// Status describes a registered task for operators
+type Status struct {
+	Name         string     `json:"name"`
+	Schedule     string     `json:"schedule"`
+	Running      bool       `json:"running"`
+	LastRun      *time.Time `json:"last_run,omitempty"`
+	LastDuration string     `json:"last_duration,omitempty"`
+	LastError    string     `json:"last_error,omitempty"`
+	NextRun      *time.Time `json:"next_run,omitempty"`
+}
+
+// task is a registered task and the outcome of its last run
+type task struct {
+	name     string
+	schedule string
+	fn       TaskFunc
+	entry    cron.EntryID
+
+	running      bool
+	lastRun      time.Time
+	lastDuration time.Duration
+	lastErr      error
+}
+
+// Scheduler runs tasks on cron schedules. A run is skipped while the previous
+// run of the same task is still in progress, and on replicas that are not
+// the leader when an Elector is set.
+type Scheduler struct {
+	mu      sync.Mutex
+	cron    *cron.Cron
+	tasks   map[string]*task
+	elector Elector
+	ctx     context.Context
+	cancel  context.CancelFunc
+}
+
+// New creates a Scheduler; call Start to begin running tasks
+func New() *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{
+		cron:   cron.New(),
+		tasks:  map[string]*task{},
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Register schedules fn under name using a standard five-field cron
+// expression or a descriptor such as "@hourly" or "@every 10m"
+func (s *Scheduler) Register(name, schedule string, fn TaskFunc) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.tasks[name]; ok {
+		return fmt.Errorf("task %q already registered", name)
+	}
+	t := &task{name: name, schedule: schedule, fn: fn}
+	id, err := s.cron.AddFunc(schedule, func() { s.run(t) })
+	if err != nil {
+		return fmt.Errorf("invalid schedule %q for task %q: %w", schedule, name, err)
+	}
+	t.entry = id
+	s.tasks[name] = t
+	return nil
+}
+
+// SetElector makes only the replica e elects as leader run tasks. It must be
+// called before Start.
+func (s *Scheduler) SetElector(e Elector) {
+	s.elector = e
+}
+
+// Start begins running the registered tasks in the background
+func (s *Scheduler) Start() {
+	s.cron.Start()
+}
+
+// Stop prevents new runs, cancels the running ones and waits for them to
+// return until ctx is done
+func (s *Scheduler) Stop(ctx context.Context) error {
+	done := s.cron.Stop()
+	s.cancel()
+	select {
+	case <-done.Done():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Statuses reports every task ordered by name
+func (s *Scheduler) Statuses() []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	statuses := make([]Status, 0, len(s.tasks))
+	for _, t := range s.tasks {
+		status := Status{
+			Name:     t.name,
+			Schedule: t.schedule,
+			Running:  t.running,
+		}
+		if !t.lastRun.IsZero() {
+			lastRun := t.lastRun
+			status.LastRun = &lastRun
+			status.LastDuration = t.lastDuration.String()
+		}
+		if t.lastErr != nil {
+			status.LastError = t.lastErr.Error()
+		}
+		if next := s.cron.Entry(t.entry).Next; !next.IsZero() {
+			status.NextRun = &next
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// run executes t unless it is already running and records the outcome
+func (s *Scheduler) run(t *task) {
+	if s.elector != nil && !s.elector.IsLeader() {
+		return
+	}
+	s.mu.Lock()
+	if t.running {
+		s.mu.Unlock()
+		log.Printf("scheduler: skipping %s, previous run still in progress\n", t.name)
+		return
+	}
+	t.running = true
+	s.mu.Unlock()
+
+	start := time.Now()
+	err := t.fn(s.ctx)
+	duration := time.Since(start)
+	if err != nil {
+		log.Printf("scheduler: %s failed after %s: %v\n", t.name, duration, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t.running = false
+	t.lastRun = start
+	t.lastDuration = duration
+	t.lastErr = err
+}
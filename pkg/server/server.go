@@ -0,0 +1,116 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/rkgcloud/crud/pkg/config"
+	"github.com/rkgcloud/crud/pkg/upgrade"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// Server is the HTTP server of the service
+type Server struct {
+	cfg      *config.Config
+	srv      *http.Server
+	upgrader *upgrade.Upgrader
+}
+
+// New creates a Server for handler on the configured listener, terminating
+// TLS when a certificate or autocert domains are configured. With an upgrader
+// the listener is inherited from, and handed over to, other processes.
+func New(cfg *config.Config, handler http.Handler, upgrader *upgrade.Upgrader) *Server {
+	srv := &http.Server{
+		Handler: handler,
+	}
+	if cfg.TLS.Autocert() {
+		m := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(cfg.TLS.AutocertDomains...),
+			Cache:      autocert.DirCache(cfg.TLS.AutocertCacheDir),
+			Email:      cfg.TLS.AutocertEmail,
+		}
+		// The TLS-ALPN-01 challenge is answered on the TLS listener itself
+		srv.TLSConfig = m.TLSConfig()
+	}
+	return &Server{cfg: cfg, srv: srv, upgrader: upgrader}
+}
+
+// ListenAndServe serves until the server fails or is shut down. A shutdown is
+// not reported as an error.
+func (s *Server) ListenAndServe() error {
+	ln, err := s.listen()
+	if err != nil {
+		return err
+	}
+	if s.upgrader != nil {
+		if err := s.upgrader.Ready(); err != nil {
+			ln.Close()
+			return err
+		}
+	}
+
+	switch {
+	case s.cfg.TLS.Autocert():
+		log.Printf("Listening on %s with certificates for %v\n", s.cfg.Listen, s.cfg.TLS.AutocertDomains)
+		err = s.srv.ServeTLS(ln, "", "")
+	case s.cfg.TLS.Enabled():
+		log.Printf("Listening on %s with TLS\n", s.cfg.Listen)
+		err = s.srv.ServeTLS(ln, s.cfg.TLS.CertFile, s.cfg.TLS.KeyFile)
+	default:
+		log.Printf("Listening on %s\n", s.cfg.Listen)
+		err = s.srv.Serve(ln)
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Shutdown stops accepting connections and waits for in-flight requests to
+// finish until ctx is done. A unix socket is removed once its listener closes.
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.srv.Shutdown(ctx)
+}
+
+// listen opens the configured listener, or inherits it during an upgrade
+func (s *Server) listen() (net.Listener, error) {
+	if s.upgrader == nil {
+		return listen(s.cfg.Listen)
+	}
+	return s.upgrader.Listen(s.cfg.Listen.Network, s.cfg.Listen.Address, func() (net.Listener, error) {
+		return listen(s.cfg.Listen)
+	})
+}
+
+// listen opens the configured TCP or unix socket listener
+func listen(l config.Listen) (net.Listener, error) {
+	if l.Network != "unix" {
+		return net.Listen(l.Network, l.Address)
+	}
+
+	// A socket left behind by a crashed process would make the bind fail
+	if info, err := os.Lstat(l.Address); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", l.Address)
+		}
+		if err := os.Remove(l.Address); err != nil {
+			return nil, fmt.Errorf("removing stale socket: %w", err)
+		}
+	}
+	ln, err := net.Listen("unix", l.Address)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(l.Address, l.SocketMode); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("setting socket permissions: %w", err)
+	}
+	return ln, nil
+}
@@ -0,0 +1,36 @@
+package shutdown
+
+import (
+	"context"
+	"sync"
+)
+
+// Group tracks background work started on behalf of requests, so shutdown
+// waits for it before releasing what it uses. The zero value is ready to use.
+type Group struct {
+	wg sync.WaitGroup
+}
+
+// Go runs fn in the background
+func (g *Group) Go(fn func()) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		fn()
+	}()
+}
+
+// Wait blocks until every function started with Go has returned or ctx is done
+func (g *Group) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		g.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
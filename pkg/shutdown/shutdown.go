@@ -0,0 +1,107 @@
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// HookFunc releases a resource during shutdown. It should return once ctx is done.
+type HookFunc func(ctx context.Context) error
+
+// hook is a named shutdown step with its own deadline
+type hook struct {
+	name    string
+	timeout time.Duration
+	fn      HookFunc
+}
+
+// Registry runs shutdown hooks in the order they were registered
+type Registry struct {
+	mu    sync.Mutex
+	hooks []hook
+	// triggered receives the reason of a shutdown not requested by a signal
+	triggered chan string
+}
+
+// NewRegistry creates an empty Registry
+func NewRegistry() *Registry {
+	return &Registry{triggered: make(chan string, 1)}
+}
+
+// Register adds a hook that is given at most timeout to complete. Hooks run
+// in registration order, so register the listener first and storage last.
+func (r *Registry) Register(name string, timeout time.Duration, fn HookFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hooks = append(r.hooks, hook{name: name, timeout: timeout, fn: fn})
+}
+
+// TriggerOn makes GracefulShutdown proceed once ch is closed, as when another
+// process takes over the service
+func (r *Registry) TriggerOn(ch <-chan struct{}, reason string) {
+	go func() {
+		<-ch
+		select {
+		case r.triggered <- reason:
+		default:
+		}
+	}()
+}
+
+// GracefulShutdown blocks until SIGINT or SIGTERM is received, or a TriggerOn
+// channel is closed, and then runs every hook
+func (r *Registry) GracefulShutdown() error {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sig)
+	select {
+	case s := <-sig:
+		log.Printf("Received %s, shutting down\n", s)
+	case reason := <-r.triggered:
+		log.Printf("%s, shutting down\n", reason)
+	}
+	return r.Run(context.Background())
+}
+
+// Run executes every hook in order, continuing past failures, and returns the
+// combined errors
+func (r *Registry) Run(ctx context.Context) error {
+	r.mu.Lock()
+	hooks := append([]hook(nil), r.hooks...)
+	r.mu.Unlock()
+
+	var errs []error
+	for _, h := range hooks {
+		start := time.Now()
+		log.Printf("shutdown: %s\n", h.name)
+		if err := runHook(ctx, h); err != nil {
+			log.Printf("shutdown: %s failed after %s: %v\n", h.name, time.Since(start), err)
+			errs = append(errs, fmt.Errorf("%s: %w", h.name, err))
+			continue
+		}
+		log.Printf("shutdown: %s done in %s\n", h.name, time.Since(start))
+	}
+	return errors.Join(errs...)
+}
+
+// runHook runs h and gives up once its timeout elapses
+func runHook(ctx context.Context, h hook) error {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- h.fn(ctx) }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("timed out after %s", h.timeout)
+	}
+}
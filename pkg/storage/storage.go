@@ -0,0 +1,65 @@
+// Package storage keeps files such as exports in a local directory or in an
+// S3-compatible object store, including Google Cloud Storage.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"time"
+
+	"github.com/rkgcloud/crud/pkg/config"
+)
+
+// ErrNotExist is returned when reading a key that holds no object
+var ErrNotExist = errors.New("object does not exist")
+
+// Storage stores objects under slash-separated keys
+type Storage interface {
+	// Put stores what is read from r under key, replacing any previous object
+	Put(ctx context.Context, key string, r io.Reader, contentType string) error
+	// Open reads the object stored under key
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the object stored under key; missing objects are not an error
+	Delete(ctx context.Context, key string) error
+	// SignedURL returns a link downloading the object under key as fileName
+	// for ttl, or "" when the backend cannot issue links and the object must
+	// be served from Open
+	SignedURL(ctx context.Context, key string, ttl time.Duration, fileName string) (string, error)
+}
+
+// Key returns the key of name stored at t under prefix. Keys are grouped by
+// prefix and day, as in exports/2006/01/02/name, so bucket lifecycle rules
+// can expire objects by prefix.
+func Key(prefix string, t time.Time, name string) string {
+	return path.Join(prefix, t.UTC().Format("2006/01/02"), name)
+}
+
+// gcsEndpoint is the S3-compatible XML API of Google Cloud Storage
+const gcsEndpoint = "storage.googleapis.com"
+
+// New creates the configured storage. The s3 and gcs backends connect with
+// the S3 settings; gcs expects HMAC keys as credentials.
+func New(cfg config.StorageConfig, s3 config.S3Config) (Storage, error) {
+	switch cfg.Backend {
+	case config.StorageS3:
+		client, err := NewS3Client(s3)
+		if err != nil {
+			return nil, err
+		}
+		return NewS3(client, cfg.Bucket), nil
+	case config.StorageGCS:
+		s3.Endpoint, s3.TLS = gcsEndpoint, true
+		client, err := NewS3Client(s3)
+		if err != nil {
+			return nil, err
+		}
+		return NewS3(client, cfg.Bucket), nil
+	case config.StorageLocal:
+		return NewLocal(cfg.Dir)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.Backend)
+	}
+}
@@ -0,0 +1,70 @@
+package tasks
+
+import (
+	"context"
+	"expvar"
+	"log"
+	"time"
+
+	"github.com/rkgcloud/crud/pkg/audit"
+	"github.com/rkgcloud/crud/pkg/models"
+	"github.com/rkgcloud/crud/pkg/scheduler"
+
+	"gorm.io/gorm"
+)
+
+// CleanupInvitations deletes invitations that expired without being accepted
+func CleanupInvitations(db *gorm.DB) scheduler.TaskFunc {
+	return func(ctx context.Context) error {
+		result := db.WithContext(ctx).Unscoped().
+			Where("accepted_at IS NULL AND expires_at < ?", time.Now()).
+			Delete(&models.Invitation{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			log.Printf("deleted %d expired invitations\n", result.RowsAffected)
+		}
+		return nil
+	}
+}
+
+// purgedUsers counts users permanently deleted by PurgeDeletedUsers
+var purgedUsers = expvar.NewInt("purged_users_total")
+
+// PurgeDeletedUsers permanently deletes users that were soft-deleted more than
+// retention ago and records the run in the audit log
+func PurgeDeletedUsers(db *gorm.DB, retention time.Duration) scheduler.TaskFunc {
+	return func(ctx context.Context) error {
+		cutoff := time.Now().Add(-retention)
+		var purged int64
+		err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			// Tags have no foreign key to the records they label
+			expired := tx.Unscoped().Model(&models.User{}).Select("id").
+				Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff)
+			if err := tx.Where("taggable_type = ? AND taggable_id IN (?)", models.TaggableUser, expired).
+				Delete(&models.Tag{}).Error; err != nil {
+				return err
+			}
+			result := tx.Unscoped().
+				Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+				Delete(&models.User{})
+			if result.Error != nil {
+				return result.Error
+			}
+			purged = result.RowsAffected
+			return audit.Record(tx, audit.ActorSystem, "users.purge", "users", map[string]any{
+				"cutoff": cutoff,
+				"purged": purged,
+			})
+		})
+		if err != nil {
+			return err
+		}
+		purgedUsers.Add(purged)
+		if purged > 0 {
+			log.Printf("purged %d users deleted before %s\n", purged, cutoff.Format(time.RFC3339))
+		}
+		return nil
+	}
+}
@@ -0,0 +1,36 @@
+// Package templates holds the helper functions registered with every
+// template, so values such as dates render the same wherever they appear
+package templates
+
+import (
+	"time"
+	"unicode/utf8"
+)
+
+// DateLayout is how formatDate renders timestamps
+const DateLayout = "Jan 2, 2006 15:04 MST"
+
+// Funcs returns the helper functions, to be registered with Funcs on a
+// text/template or html/template before parsing:
+//
+//	formatDate TIME      renders TIME with DateLayout
+//	truncate N TEXT      shortens TEXT to N characters, ending it with "…"
+func Funcs() map[string]any {
+	return map[string]any{
+		"formatDate": formatDate,
+		"truncate":   truncate,
+	}
+}
+
+func formatDate(t time.Time) string {
+	return t.Format(DateLayout)
+}
+
+// truncate takes the length first so it can end a pipeline: {{.Name | truncate 40}}
+func truncate(n int, s string) string {
+	if n <= 0 || utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:n-1]) + "…"
+}
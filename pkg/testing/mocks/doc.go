@@ -0,0 +1,5 @@
+// Package mocks holds mocks of the repository and handler interfaces for
+// tests that should not need a database or mail server. The mocks are
+// generated by mockery from .mockery.yaml; run `make mocks` after changing
+// an interface rather than editing them.
+package mocks
@@ -0,0 +1,134 @@
+// Code generated by mockery. DO NOT EDIT.
+
+package mocks
+
+import (
+	time "time"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// Mailer is an autogenerated mock type for the Mailer type
+type Mailer struct {
+	mock.Mock
+}
+
+type Mailer_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *Mailer) EXPECT() *Mailer_Expecter {
+	return &Mailer_Expecter{mock: &_m.Mock}
+}
+
+// SendInvitation provides a mock function with given fields: to, role, link, expiresAt
+func (_m *Mailer) SendInvitation(to string, role string, link string, expiresAt time.Time) error {
+	ret := _m.Called(to, role, link, expiresAt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SendInvitation")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string, string, time.Time) error); ok {
+		r0 = rf(to, role, link, expiresAt)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Mailer_SendInvitation_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SendInvitation'
+type Mailer_SendInvitation_Call struct {
+	*mock.Call
+}
+
+// SendInvitation is a helper method to define mock.On call
+//   - to string
+//   - role string
+//   - link string
+//   - expiresAt time.Time
+func (_e *Mailer_Expecter) SendInvitation(to interface{}, role interface{}, link interface{}, expiresAt interface{}) *Mailer_SendInvitation_Call {
+	return &Mailer_SendInvitation_Call{Call: _e.mock.On("SendInvitation", to, role, link, expiresAt)}
+}
+
+func (_c *Mailer_SendInvitation_Call) Run(run func(to string, role string, link string, expiresAt time.Time)) *Mailer_SendInvitation_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].(string), args[2].(string), args[3].(time.Time))
+	})
+	return _c
+}
+
+func (_c *Mailer_SendInvitation_Call) Return(_a0 error) *Mailer_SendInvitation_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Mailer_SendInvitation_Call) RunAndReturn(run func(string, string, string, time.Time) error) *Mailer_SendInvitation_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SendVerification provides a mock function with given fields: to, name, link, expiresAt
+func (_m *Mailer) SendVerification(to string, name string, link string, expiresAt time.Time) error {
+	ret := _m.Called(to, name, link, expiresAt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SendVerification")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string, string, time.Time) error); ok {
+		r0 = rf(to, name, link, expiresAt)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Mailer_SendVerification_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SendVerification'
+type Mailer_SendVerification_Call struct {
+	*mock.Call
+}
+
+// SendVerification is a helper method to define mock.On call
+//   - to string
+//   - name string
+//   - link string
+//   - expiresAt time.Time
+func (_e *Mailer_Expecter) SendVerification(to interface{}, name interface{}, link interface{}, expiresAt interface{}) *Mailer_SendVerification_Call {
+	return &Mailer_SendVerification_Call{Call: _e.mock.On("SendVerification", to, name, link, expiresAt)}
+}
+
+func (_c *Mailer_SendVerification_Call) Run(run func(to string, name string, link string, expiresAt time.Time)) *Mailer_SendVerification_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].(string), args[2].(string), args[3].(time.Time))
+	})
+	return _c
+}
+
+func (_c *Mailer_SendVerification_Call) Return(_a0 error) *Mailer_SendVerification_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Mailer_SendVerification_Call) RunAndReturn(run func(string, string, string, time.Time) error) *Mailer_SendVerification_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMailer creates a new instance of Mailer. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMailer(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *Mailer {
+	mock := &Mailer{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,741 @@
+// Code generated by mockery. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	filter "github.com/rkgcloud/crud/pkg/filter"
+	mock "github.com/stretchr/testify/mock"
+
+	models "github.com/rkgcloud/crud/pkg/models"
+
+	repository "github.com/rkgcloud/crud/pkg/repository"
+)
+
+// Users is an autogenerated mock type for the Users type
+type Users struct {
+	mock.Mock
+}
+
+type Users_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *Users) EXPECT() *Users_Expecter {
+	return &Users_Expecter{mock: &_m.Mock}
+}
+
+// AddTag provides a mock function with given fields: ctx, user, tag
+func (_m *Users) AddTag(ctx context.Context, user models.User, tag string) error {
+	ret := _m.Called(ctx, user, tag)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddTag")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.User, string) error); ok {
+		r0 = rf(ctx, user, tag)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Users_AddTag_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AddTag'
+type Users_AddTag_Call struct {
+	*mock.Call
+}
+
+// AddTag is a helper method to define mock.On call
+//   - ctx context.Context
+//   - user models.User
+//   - tag string
+func (_e *Users_Expecter) AddTag(ctx interface{}, user interface{}, tag interface{}) *Users_AddTag_Call {
+	return &Users_AddTag_Call{Call: _e.mock.On("AddTag", ctx, user, tag)}
+}
+
+func (_c *Users_AddTag_Call) Run(run func(ctx context.Context, user models.User, tag string)) *Users_AddTag_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(models.User), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *Users_AddTag_Call) Return(_a0 error) *Users_AddTag_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Users_AddTag_Call) RunAndReturn(run func(context.Context, models.User, string) error) *Users_AddTag_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Create provides a mock function with given fields: ctx, user
+func (_m *Users) Create(ctx context.Context, user *models.User) error {
+	ret := _m.Called(ctx, user)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Create")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.User) error); ok {
+		r0 = rf(ctx, user)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Users_Create_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Create'
+type Users_Create_Call struct {
+	*mock.Call
+}
+
+// Create is a helper method to define mock.On call
+//   - ctx context.Context
+//   - user *models.User
+func (_e *Users_Expecter) Create(ctx interface{}, user interface{}) *Users_Create_Call {
+	return &Users_Create_Call{Call: _e.mock.On("Create", ctx, user)}
+}
+
+func (_c *Users_Create_Call) Run(run func(ctx context.Context, user *models.User)) *Users_Create_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*models.User))
+	})
+	return _c
+}
+
+func (_c *Users_Create_Call) Return(_a0 error) *Users_Create_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Users_Create_Call) RunAndReturn(run func(context.Context, *models.User) error) *Users_Create_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Delete provides a mock function with given fields: ctx, user
+func (_m *Users) Delete(ctx context.Context, user *models.User) error {
+	ret := _m.Called(ctx, user)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Delete")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.User) error); ok {
+		r0 = rf(ctx, user)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Users_Delete_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Delete'
+type Users_Delete_Call struct {
+	*mock.Call
+}
+
+// Delete is a helper method to define mock.On call
+//   - ctx context.Context
+//   - user *models.User
+func (_e *Users_Expecter) Delete(ctx interface{}, user interface{}) *Users_Delete_Call {
+	return &Users_Delete_Call{Call: _e.mock.On("Delete", ctx, user)}
+}
+
+func (_c *Users_Delete_Call) Run(run func(ctx context.Context, user *models.User)) *Users_Delete_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*models.User))
+	})
+	return _c
+}
+
+func (_c *Users_Delete_Call) Return(_a0 error) *Users_Delete_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Users_Delete_Call) RunAndReturn(run func(context.Context, *models.User) error) *Users_Delete_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Erase provides a mock function with given fields: ctx, user
+func (_m *Users) Erase(ctx context.Context, user *models.User) (repository.ErasureReport, error) {
+	ret := _m.Called(ctx, user)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Erase")
+	}
+
+	var r0 repository.ErasureReport
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.User) (repository.ErasureReport, error)); ok {
+		return rf(ctx, user)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.User) repository.ErasureReport); ok {
+		r0 = rf(ctx, user)
+	} else {
+		r0 = ret.Get(0).(repository.ErasureReport)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.User) error); ok {
+		r1 = rf(ctx, user)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Users_Erase_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Erase'
+type Users_Erase_Call struct {
+	*mock.Call
+}
+
+// Erase is a helper method to define mock.On call
+//   - ctx context.Context
+//   - user *models.User
+func (_e *Users_Expecter) Erase(ctx interface{}, user interface{}) *Users_Erase_Call {
+	return &Users_Erase_Call{Call: _e.mock.On("Erase", ctx, user)}
+}
+
+func (_c *Users_Erase_Call) Run(run func(ctx context.Context, user *models.User)) *Users_Erase_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*models.User))
+	})
+	return _c
+}
+
+func (_c *Users_Erase_Call) Return(_a0 repository.ErasureReport, _a1 error) *Users_Erase_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Users_Erase_Call) RunAndReturn(run func(context.Context, *models.User) (repository.ErasureReport, error)) *Users_Erase_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Get provides a mock function with given fields: ctx, id
+func (_m *Users) Get(ctx context.Context, id uint) (models.User, error) {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Get")
+	}
+
+	var r0 models.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint) (models.User, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uint) models.User); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Get(0).(models.User)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uint) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Users_Get_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Get'
+type Users_Get_Call struct {
+	*mock.Call
+}
+
+// Get is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id uint
+func (_e *Users_Expecter) Get(ctx interface{}, id interface{}) *Users_Get_Call {
+	return &Users_Get_Call{Call: _e.mock.On("Get", ctx, id)}
+}
+
+func (_c *Users_Get_Call) Run(run func(ctx context.Context, id uint)) *Users_Get_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(uint))
+	})
+	return _c
+}
+
+func (_c *Users_Get_Call) Return(_a0 models.User, _a1 error) *Users_Get_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Users_Get_Call) RunAndReturn(run func(context.Context, uint) (models.User, error)) *Users_Get_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// List provides a mock function with given fields: ctx, conds, offset, limit
+func (_m *Users) List(ctx context.Context, conds []filter.Condition, offset int, limit int) ([]models.User, int64, error) {
+	ret := _m.Called(ctx, conds, offset, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for List")
+	}
+
+	var r0 []models.User
+	var r1 int64
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, []filter.Condition, int, int) ([]models.User, int64, error)); ok {
+		return rf(ctx, conds, offset, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []filter.Condition, int, int) []models.User); ok {
+		r0 = rf(ctx, conds, offset, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []filter.Condition, int, int) int64); ok {
+		r1 = rf(ctx, conds, offset, limit)
+	} else {
+		r1 = ret.Get(1).(int64)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, []filter.Condition, int, int) error); ok {
+		r2 = rf(ctx, conds, offset, limit)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// Users_List_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'List'
+type Users_List_Call struct {
+	*mock.Call
+}
+
+// List is a helper method to define mock.On call
+//   - ctx context.Context
+//   - conds []filter.Condition
+//   - offset int
+//   - limit int
+func (_e *Users_Expecter) List(ctx interface{}, conds interface{}, offset interface{}, limit interface{}) *Users_List_Call {
+	return &Users_List_Call{Call: _e.mock.On("List", ctx, conds, offset, limit)}
+}
+
+func (_c *Users_List_Call) Run(run func(ctx context.Context, conds []filter.Condition, offset int, limit int)) *Users_List_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].([]filter.Condition), args[2].(int), args[3].(int))
+	})
+	return _c
+}
+
+func (_c *Users_List_Call) Return(_a0 []models.User, _a1 int64, _a2 error) *Users_List_Call {
+	_c.Call.Return(_a0, _a1, _a2)
+	return _c
+}
+
+func (_c *Users_List_Call) RunAndReturn(run func(context.Context, []filter.Condition, int, int) ([]models.User, int64, error)) *Users_List_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListDeleted provides a mock function with given fields: ctx, offset, limit
+func (_m *Users) ListDeleted(ctx context.Context, offset int, limit int) ([]models.User, int64, error) {
+	ret := _m.Called(ctx, offset, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListDeleted")
+	}
+
+	var r0 []models.User
+	var r1 int64
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int) ([]models.User, int64, error)); ok {
+		return rf(ctx, offset, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, int) []models.User); ok {
+		r0 = rf(ctx, offset, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, int) int64); ok {
+		r1 = rf(ctx, offset, limit)
+	} else {
+		r1 = ret.Get(1).(int64)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, int, int) error); ok {
+		r2 = rf(ctx, offset, limit)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// Users_ListDeleted_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListDeleted'
+type Users_ListDeleted_Call struct {
+	*mock.Call
+}
+
+// ListDeleted is a helper method to define mock.On call
+//   - ctx context.Context
+//   - offset int
+//   - limit int
+func (_e *Users_Expecter) ListDeleted(ctx interface{}, offset interface{}, limit interface{}) *Users_ListDeleted_Call {
+	return &Users_ListDeleted_Call{Call: _e.mock.On("ListDeleted", ctx, offset, limit)}
+}
+
+func (_c *Users_ListDeleted_Call) Run(run func(ctx context.Context, offset int, limit int)) *Users_ListDeleted_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int), args[2].(int))
+	})
+	return _c
+}
+
+func (_c *Users_ListDeleted_Call) Return(_a0 []models.User, _a1 int64, _a2 error) *Users_ListDeleted_Call {
+	_c.Call.Return(_a0, _a1, _a2)
+	return _c
+}
+
+func (_c *Users_ListDeleted_Call) RunAndReturn(run func(context.Context, int, int) ([]models.User, int64, error)) *Users_ListDeleted_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// MarkVerified provides a mock function with given fields: ctx, user
+func (_m *Users) MarkVerified(ctx context.Context, user *models.User) error {
+	ret := _m.Called(ctx, user)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MarkVerified")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.User) error); ok {
+		r0 = rf(ctx, user)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Users_MarkVerified_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'MarkVerified'
+type Users_MarkVerified_Call struct {
+	*mock.Call
+}
+
+// MarkVerified is a helper method to define mock.On call
+//   - ctx context.Context
+//   - user *models.User
+func (_e *Users_Expecter) MarkVerified(ctx interface{}, user interface{}) *Users_MarkVerified_Call {
+	return &Users_MarkVerified_Call{Call: _e.mock.On("MarkVerified", ctx, user)}
+}
+
+func (_c *Users_MarkVerified_Call) Run(run func(ctx context.Context, user *models.User)) *Users_MarkVerified_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*models.User))
+	})
+	return _c
+}
+
+func (_c *Users_MarkVerified_Call) Return(_a0 error) *Users_MarkVerified_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Users_MarkVerified_Call) RunAndReturn(run func(context.Context, *models.User) error) *Users_MarkVerified_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RecordUndeliverable provides a mock function with given fields: ctx, user, domain
+func (_m *Users) RecordUndeliverable(ctx context.Context, user models.User, domain string) error {
+	ret := _m.Called(ctx, user, domain)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RecordUndeliverable")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.User, string) error); ok {
+		r0 = rf(ctx, user, domain)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Users_RecordUndeliverable_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RecordUndeliverable'
+type Users_RecordUndeliverable_Call struct {
+	*mock.Call
+}
+
+// RecordUndeliverable is a helper method to define mock.On call
+//   - ctx context.Context
+//   - user models.User
+//   - domain string
+func (_e *Users_Expecter) RecordUndeliverable(ctx interface{}, user interface{}, domain interface{}) *Users_RecordUndeliverable_Call {
+	return &Users_RecordUndeliverable_Call{Call: _e.mock.On("RecordUndeliverable", ctx, user, domain)}
+}
+
+func (_c *Users_RecordUndeliverable_Call) Run(run func(ctx context.Context, user models.User, domain string)) *Users_RecordUndeliverable_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(models.User), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *Users_RecordUndeliverable_Call) Return(_a0 error) *Users_RecordUndeliverable_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Users_RecordUndeliverable_Call) RunAndReturn(run func(context.Context, models.User, string) error) *Users_RecordUndeliverable_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RemoveTag provides a mock function with given fields: ctx, user, tag
+func (_m *Users) RemoveTag(ctx context.Context, user models.User, tag string) error {
+	ret := _m.Called(ctx, user, tag)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RemoveTag")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.User, string) error); ok {
+		r0 = rf(ctx, user, tag)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Users_RemoveTag_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RemoveTag'
+type Users_RemoveTag_Call struct {
+	*mock.Call
+}
+
+// RemoveTag is a helper method to define mock.On call
+//   - ctx context.Context
+//   - user models.User
+//   - tag string
+func (_e *Users_Expecter) RemoveTag(ctx interface{}, user interface{}, tag interface{}) *Users_RemoveTag_Call {
+	return &Users_RemoveTag_Call{Call: _e.mock.On("RemoveTag", ctx, user, tag)}
+}
+
+func (_c *Users_RemoveTag_Call) Run(run func(ctx context.Context, user models.User, tag string)) *Users_RemoveTag_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(models.User), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *Users_RemoveTag_Call) Return(_a0 error) *Users_RemoveTag_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Users_RemoveTag_Call) RunAndReturn(run func(context.Context, models.User, string) error) *Users_RemoveTag_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Suggest provides a mock function with given fields: ctx, prefix, limit
+func (_m *Users) Suggest(ctx context.Context, prefix string, limit int) ([]models.User, error) {
+	ret := _m.Called(ctx, prefix, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Suggest")
+	}
+
+	var r0 []models.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) ([]models.User, error)); ok {
+		return rf(ctx, prefix, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) []models.User); ok {
+		r0 = rf(ctx, prefix, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, int) error); ok {
+		r1 = rf(ctx, prefix, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Users_Suggest_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Suggest'
+type Users_Suggest_Call struct {
+	*mock.Call
+}
+
+// Suggest is a helper method to define mock.On call
+//   - ctx context.Context
+//   - prefix string
+//   - limit int
+func (_e *Users_Expecter) Suggest(ctx interface{}, prefix interface{}, limit interface{}) *Users_Suggest_Call {
+	return &Users_Suggest_Call{Call: _e.mock.On("Suggest", ctx, prefix, limit)}
+}
+
+func (_c *Users_Suggest_Call) Run(run func(ctx context.Context, prefix string, limit int)) *Users_Suggest_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(int))
+	})
+	return _c
+}
+
+func (_c *Users_Suggest_Call) Return(_a0 []models.User, _a1 error) *Users_Suggest_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Users_Suggest_Call) RunAndReturn(run func(context.Context, string, int) ([]models.User, error)) *Users_Suggest_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Tags provides a mock function with given fields: ctx, user
+func (_m *Users) Tags(ctx context.Context, user models.User) ([]string, error) {
+	ret := _m.Called(ctx, user)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Tags")
+	}
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.User) ([]string, error)); ok {
+		return rf(ctx, user)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, models.User) []string); ok {
+		r0 = rf(ctx, user)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, models.User) error); ok {
+		r1 = rf(ctx, user)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Users_Tags_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Tags'
+type Users_Tags_Call struct {
+	*mock.Call
+}
+
+// Tags is a helper method to define mock.On call
+//   - ctx context.Context
+//   - user models.User
+func (_e *Users_Expecter) Tags(ctx interface{}, user interface{}) *Users_Tags_Call {
+	return &Users_Tags_Call{Call: _e.mock.On("Tags", ctx, user)}
+}
+
+func (_c *Users_Tags_Call) Run(run func(ctx context.Context, user models.User)) *Users_Tags_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(models.User))
+	})
+	return _c
+}
+
+func (_c *Users_Tags_Call) Return(_a0 []string, _a1 error) *Users_Tags_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Users_Tags_Call) RunAndReturn(run func(context.Context, models.User) ([]string, error)) *Users_Tags_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Update provides a mock function with given fields: ctx, user
+func (_m *Users) Update(ctx context.Context, user *models.User) error {
+	ret := _m.Called(ctx, user)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Update")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.User) error); ok {
+		r0 = rf(ctx, user)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Users_Update_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Update'
+type Users_Update_Call struct {
+	*mock.Call
+}
+
+// Update is a helper method to define mock.On call
+//   - ctx context.Context
+//   - user *models.User
+func (_e *Users_Expecter) Update(ctx interface{}, user interface{}) *Users_Update_Call {
+	return &Users_Update_Call{Call: _e.mock.On("Update", ctx, user)}
+}
+
+func (_c *Users_Update_Call) Run(run func(ctx context.Context, user *models.User)) *Users_Update_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*models.User))
+	})
+	return _c
+}
+
+func (_c *Users_Update_Call) Return(_a0 error) *Users_Update_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Users_Update_Call) RunAndReturn(run func(context.Context, *models.User) error) *Users_Update_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewUsers creates a new instance of Users. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewUsers(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *Users {
+	mock := &Users{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
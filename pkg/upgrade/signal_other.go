@@ -0,0 +1,9 @@
+//go:build !unix
+
+package upgrade
+
+import "os"
+
+// upgradeSignals is empty where there is no SIGUSR2; tableflip does not
+// support these systems either
+var upgradeSignals []os.Signal
@@ -0,0 +1,11 @@
+//go:build unix
+
+package upgrade
+
+import (
+	"os"
+	"syscall"
+)
+
+// upgradeSignals trigger an upgrade
+var upgradeSignals = []os.Signal{syscall.SIGUSR2}
@@ -0,0 +1,84 @@
+package upgrade
+
+import (
+	"context"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+
+	"github.com/rkgcloud/crud/pkg/config"
+
+	"github.com/cloudflare/tableflip"
+)
+
+// Upgrader restarts the service without dropping connections. On SIGUSR2 the
+// binary is started again with the listening socket; once the new process is
+// ready, Exit is closed and this process drains its requests and exits.
+type Upgrader struct {
+	upg *tableflip.Upgrader
+}
+
+// New creates an Upgrader, inheriting the listeners of the parent process
+// when this process was started by an upgrade
+func New(cfg config.UpgradeConfig) (*Upgrader, error) {
+	upg, err := tableflip.New(tableflip.Options{
+		PIDFile:        cfg.PIDFile,
+		UpgradeTimeout: cfg.Timeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if upg.HasParent() {
+		log.Println("Taking over the listener of the previous process")
+	}
+	return &Upgrader{upg: upg}, nil
+}
+
+// Listen returns the listener inherited for network and address, or the one
+// opened by listen, which is then handed over on the next upgrade
+func (u *Upgrader) Listen(network, address string, listen func() (net.Listener, error)) (net.Listener, error) {
+	return u.upg.ListenWithCallback(network, address, func(string, string) (net.Listener, error) {
+		return listen()
+	})
+}
+
+// Ready reports that this process accepts connections, letting the parent
+// process exit, and writes the PID file
+func (u *Upgrader) Ready() error {
+	return u.upg.Ready()
+}
+
+// Exit is closed once a new process has taken over
+func (u *Upgrader) Exit() <-chan struct{} {
+	return u.upg.Exit()
+}
+
+// Watch upgrades on every SIGUSR2 until ctx is done. A failed upgrade leaves
+// this process serving.
+func (u *Upgrader) Watch(ctx context.Context) {
+	if len(upgradeSignals) == 0 {
+		return
+	}
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, upgradeSignals...)
+	defer signal.Stop(sig)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case s := <-sig:
+			log.Printf("Received %s, upgrading\n", s)
+			if err := u.upg.Upgrade(); err != nil {
+				log.Println("Upgrade failed:", err)
+			}
+		}
+	}
+}
+
+// Stop prevents further upgrades. Unless a new process took over, unix
+// sockets are removed as they would be without the Upgrader.
+func (u *Upgrader) Stop(ctx context.Context) error {
+	u.upg.Stop()
+	return nil
+}
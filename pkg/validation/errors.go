@@ -0,0 +1,71 @@
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// FieldError describes why the value of a single request field was rejected
+type FieldError struct {
+	// Field is the JSON name of the field
+	Field string `json:"field"`
+	// Rule is the validation rule the value failed
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Error returns the field and message
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// Fields returns the field errors within err, or nil when err is not caused by
+// invalid fields
+func Fields(err error) []FieldError {
+	var (
+		validationErrs validator.ValidationErrors
+		fieldErr       *FieldError
+		typeErr        *json.UnmarshalTypeError
+	)
+	switch {
+	case errors.As(err, &validationErrs):
+		fields := make([]FieldError, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			fields = append(fields, FieldError{Field: fe.Field(), Rule: fe.Tag(), Message: message(fe)})
+		}
+		return fields
+	case errors.As(err, &fieldErr):
+		return []FieldError{*fieldErr}
+	case errors.As(err, &typeErr):
+		return []FieldError{{Field: typeErr.Field, Rule: "type", Message: "must be of type " + typeErr.Type.String()}}
+	}
+	return nil
+}
+
+// message describes a failed validation rule
+func message(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email_address":
+		return "must be a valid email address"
+	case "phone":
+		return "must be a valid phone number from an accepted region"
+	case "country":
+		return "must be an ISO 3166-1 alpha-2 country code"
+	case "timezone":
+		return "must be an IANA time zone such as Europe/Paris"
+	case "bcp47_language_tag":
+		return "must be a BCP 47 language tag such as en-US"
+	case "len":
+		return fmt.Sprintf("must be %s characters long", fe.Param())
+	case "max":
+		return fmt.Sprintf("must be at most %s characters long", fe.Param())
+	case "oneof":
+		return fmt.Sprintf("must be one of %s", fe.Param())
+	}
+	return fmt.Sprintf("failed the %s rule", fe.Tag())
+}
@@ -0,0 +1,223 @@
+package validation
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"net/mail"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/rkgcloud/crud/pkg/config"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/nyaruka/phonenumbers"
+	"golang.org/x/text/language"
+)
+
+var (
+	mu     sync.RWMutex
+	phones = config.PhoneConfig{DefaultRegion: "US"}
+	// metadataSchema maps the metadata keys users may carry to their type
+	metadataSchema = map[string]string{}
+)
+
+// Register adds the custom validators to gin's binding engine and reports
+// field errors under their JSON names. Phone numbers are validated against cfg
+// and user metadata against schema, which maps keys to their type.
+//
+// Validators available as binding tags:
+//   - email_address: an address net/mail parses, without a display name
+//   - phone: a phone number from an allowed region
+//   - country: an ISO 3166-1 alpha-2 country code
+func Register(cfg config.PhoneConfig, schema map[string]string) error {
+	mu.Lock()
+	phones = cfg
+	metadataSchema = schema
+	mu.Unlock()
+
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return fmt.Errorf("unsupported validator engine %T", binding.Validator.Engine())
+	}
+	v.RegisterTagNameFunc(jsonName)
+	for tag, fn := range map[string]validator.Func{
+		"email_address": func(fl validator.FieldLevel) bool {
+			_, err := NormalizeEmail(fl.Field().String())
+			return err == nil
+		},
+		"phone": func(fl validator.FieldLevel) bool {
+			_, _, err := NormalizePhone(fl.Field().String())
+			return err == nil
+		},
+		"country": func(fl validator.FieldLevel) bool {
+			_, err := NormalizeCountry(fl.Field().String())
+			return err == nil
+		},
+	} {
+		if err := v.RegisterValidation(tag, fn); err != nil {
+			return fmt.Errorf("registering %s validator: %w", tag, err)
+		}
+	}
+	return nil
+}
+
+// jsonName names a field after its JSON key
+func jsonName(field reflect.StructField) string {
+	name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	}
+	return name
+}
+
+// NormalizeEmail trims and lowercases a bare email address, rejecting anything
+// net/mail cannot parse or that carries a display name
+func NormalizeEmail(address string) (string, error) {
+	address = strings.TrimSpace(address)
+	parsed, err := mail.ParseAddress(address)
+	if err != nil || parsed.Address != address || !strings.Contains(EmailDomain(address), ".") {
+		return "", &FieldError{Field: "email", Rule: "email_address", Message: fmt.Sprintf("%q is not a valid email address", address)}
+	}
+	return strings.ToLower(address), nil
+}
+
+// EmailDomain returns the part of an address after its last @
+func EmailDomain(address string) string {
+	return address[strings.LastIndex(address, "@")+1:]
+}
+
+// NormalizePhone parses a phone number, returning it in E.164 format along
+// with its international display format
+func NormalizePhone(raw string) (e164, display string, err error) {
+	mu.RLock()
+	cfg := phones
+	mu.RUnlock()
+
+	num, err := phonenumbers.Parse(raw, cfg.DefaultRegion)
+	if err != nil || !phonenumbers.IsValidNumber(num) {
+		return "", "", &FieldError{Field: "phone", Rule: "phone", Message: fmt.Sprintf("%q is not a valid phone number", raw)}
+	}
+	region := phonenumbers.GetRegionCodeForNumber(num)
+	if len(cfg.AllowedRegions) > 0 && !slices.Contains(cfg.AllowedRegions, region) {
+		return "", "", &FieldError{Field: "phone", Rule: "phone", Message: fmt.Sprintf("phone numbers from %s are not accepted", region)}
+	}
+	return phonenumbers.Format(num, phonenumbers.E164), phonenumbers.Format(num, phonenumbers.INTERNATIONAL), nil
+}
+
+// CheckMetadata checks that every key of values is registered in the metadata
+// schema and holds a value of its type
+func CheckMetadata(values map[string]any) error {
+	mu.RLock()
+	schema := metadataSchema
+	mu.RUnlock()
+
+	for _, key := range slices.Sorted(maps.Keys(values)) {
+		kind, ok := schema[key]
+		if !ok {
+			return &FieldError{Field: "metadata." + key, Rule: "metadata", Message: "is not a registered metadata key"}
+		}
+		var valid bool
+		switch values[key].(type) {
+		case string:
+			valid = kind == config.MetadataString
+		case float64:
+			valid = kind == config.MetadataNumber
+		case bool:
+			valid = kind == config.MetadataBool
+		}
+		if !valid {
+			return &FieldError{Field: "metadata." + key, Rule: "metadata", Message: "must be a " + kind}
+		}
+	}
+	return nil
+}
+
+// ParseMetadata parses raw, taken from a query parameter, as a value of the
+// registered metadata key
+func ParseMetadata(key, raw string) (any, error) {
+	mu.RLock()
+	kind, ok := metadataSchema[key]
+	mu.RUnlock()
+
+	field := "metadata." + key
+	switch kind {
+	case config.MetadataString:
+		return raw, nil
+	case config.MetadataNumber:
+		if n, err := strconv.ParseFloat(raw, 64); err == nil && !math.IsNaN(n) && !math.IsInf(n, 0) {
+			return n, nil
+		}
+	case config.MetadataBool:
+		if b, err := strconv.ParseBool(raw); err == nil {
+			return b, nil
+		}
+	}
+	if !ok {
+		return nil, &FieldError{Field: field, Rule: "metadata", Message: "is not a registered metadata key"}
+	}
+	return nil, &FieldError{Field: field, Rule: "metadata", Message: "must be a " + kind}
+}
+
+// NormalizeCountry returns the upper-case ISO 3166-1 alpha-2 code of a country
+func NormalizeCountry(code string) (string, error) {
+	region, err := language.ParseRegion(strings.TrimSpace(code))
+	if err != nil || !region.IsCountry() || len(strings.TrimSpace(code)) != 2 {
+		return "", &FieldError{Field: "country", Rule: "country", Message: fmt.Sprintf("%q is not an ISO 3166-1 alpha-2 country code", code)}
+	}
+	return region.String(), nil
+}
+
+// postalCodeFormats are the postal code formats of countries that use them.
+// Countries without an entry accept any postal code, or none.
+var postalCodeFormats = map[string]*regexp.Regexp{
+	"AU": regexp.MustCompile(`^\d{4}$`),
+	"AT": regexp.MustCompile(`^\d{4}$`),
+	"BE": regexp.MustCompile(`^\d{4}$`),
+	"BR": regexp.MustCompile(`^\d{5}-?\d{3}$`),
+	"CA": regexp.MustCompile(`^[A-Za-z]\d[A-Za-z] ?\d[A-Za-z]\d$`),
+	"CH": regexp.MustCompile(`^\d{4}$`),
+	"DE": regexp.MustCompile(`^\d{5}$`),
+	"DK": regexp.MustCompile(`^\d{4}$`),
+	"ES": regexp.MustCompile(`^\d{5}$`),
+	"FR": regexp.MustCompile(`^\d{5}$`),
+	"GB": regexp.MustCompile(`^[A-Za-z]{1,2}\d[A-Za-z\d]? ?\d[A-Za-z]{2}$`),
+	"IN": regexp.MustCompile(`^\d{6}$`),
+	"IT": regexp.MustCompile(`^\d{5}$`),
+	"JP": regexp.MustCompile(`^\d{3}-?\d{4}$`),
+	"MX": regexp.MustCompile(`^\d{5}$`),
+	"NL": regexp.MustCompile(`^\d{4} ?[A-Za-z]{2}$`),
+	"NO": regexp.MustCompile(`^\d{4}$`),
+	"PL": regexp.MustCompile(`^\d{2}-\d{3}$`),
+	"SE": regexp.MustCompile(`^\d{3} ?\d{2}$`),
+	"US": regexp.MustCompile(`^\d{5}(-\d{4})?$`),
+}
+
+// countriesRequiringPostalCode reject addresses without a postal code
+var countriesRequiringPostalCode = map[string]bool{
+	"BR": true, "CA": true, "DE": true, "FR": true, "GB": true, "IN": true, "NL": true, "US": true,
+}
+
+// PostalCode checks a postal code against the format of its country
+func PostalCode(country, code string) error {
+	country = strings.ToUpper(strings.TrimSpace(country))
+	code = strings.TrimSpace(code)
+	if code == "" {
+		if countriesRequiringPostalCode[country] {
+			return &FieldError{Field: "postal_code", Rule: "required", Message: fmt.Sprintf("a postal code is required for %s addresses", country)}
+		}
+		return nil
+	}
+	if format, ok := postalCodeFormats[country]; ok && !format.MatchString(code) {
+		return &FieldError{Field: "postal_code", Rule: "postal_code", Message: fmt.Sprintf("%q is not a valid postal code for %s", code, country)}
+	}
+	return nil
+}
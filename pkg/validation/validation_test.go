@@ -0,0 +1,43 @@
+package validation
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeEmail(t *testing.T) {
+	tests := []struct {
+		address string
+		want    string
+		err     bool
+	}{
+		{address: "jane@example.com", want: "jane@example.com"},
+		{address: "  Jane.Doe@Example.COM ", want: "jane.doe@example.com"},
+		{address: "jane+tag@mail.example.co.uk", want: "jane+tag@mail.example.co.uk"},
+		{address: "", err: true},
+		{address: "jane", err: true},
+		{address: "jane@", err: true},
+		{address: "@example.com", err: true},
+		{address: "jane@localhost", err: true},
+		{address: "jane doe@example.com", err: true},
+		{address: "Jane <jane@example.com>", err: true},
+		{address: "<jane@example.com>", err: true},
+		{address: "jane@example.com, joe@example.com", err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			got, err := NormalizeEmail(tt.address)
+			if tt.err {
+				var fieldErr *FieldError
+				require.True(t, errors.As(err, &fieldErr), "got %v", err)
+				assert.Equal(t, "email", fieldErr.Field)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package verification
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	oldSecret = []byte("old-secret-old-secret-old-secret")
+	newSecret = []byte("new-secret-new-secret-new-secret")
+)
+
+func TestVerifierRoundTrip(t *testing.T) {
+	v := NewVerifier([][]byte{newSecret}, "email", time.Hour)
+	id, subject, err := v.Verify(v.Token(42, "ann@example.com"))
+	require.NoError(t, err)
+	assert.Equal(t, uint(42), id)
+	assert.Equal(t, "ann@example.com", subject)
+}
+
+func TestVerifierSubjectWithSeparator(t *testing.T) {
+	v := NewVerifier([][]byte{newSecret}, "email", time.Hour)
+	_, subject, err := v.Verify(v.Token(1, "a|b|c"))
+	require.NoError(t, err)
+	assert.Equal(t, "a|b|c", subject)
+}
+
+func TestVerifierExpired(t *testing.T) {
+	v := NewVerifier([][]byte{newSecret}, "email", -2*time.Second)
+	_, _, err := v.Verify(v.Token(1, "ann@example.com"))
+	assert.ErrorIs(t, err, ErrExpiredToken)
+}
+
+func TestVerifierRejects(t *testing.T) {
+	email := NewVerifier([][]byte{newSecret}, "email", time.Hour)
+	token := email.Token(1, "ann@example.com")
+	_, signature, _ := strings.Cut(token, ".")
+
+	tests := []struct {
+		name     string
+		verifier *Verifier
+		token    string
+	}{
+		{"token of another purpose", NewVerifier([][]byte{newSecret}, "invitation", time.Hour), token},
+		{"token of an unknown secret", NewVerifier([][]byte{oldSecret}, "email", time.Hour), token},
+		{"tampered payload", email, encode([]byte("2|9999999999|ann@example.com")) + "." + signature},
+		{"missing signature", email, encode([]byte("1|9999999999|ann@example.com"))},
+		{"not base64", email, "!!!.???"},
+		{"empty", email, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := tt.verifier.Verify(tt.token)
+			assert.ErrorIs(t, err, ErrInvalidToken)
+		})
+	}
+}
+
+func TestVerifierRotation(t *testing.T) {
+	before := NewVerifier([][]byte{oldSecret}, "email", time.Hour)
+	issued := before.Token(7, "ann@example.com")
+
+	// The new secret signs and the old one still verifies
+	rotated := NewVerifier([][]byte{newSecret, oldSecret}, "email", time.Hour)
+	id, _, err := rotated.Verify(issued)
+	require.NoError(t, err)
+	assert.Equal(t, uint(7), id)
+
+	fresh := rotated.Token(8, "bob@example.com")
+	_, _, err = NewVerifier([][]byte{newSecret}, "email", time.Hour).Verify(fresh)
+	assert.NoError(t, err, "new tokens are signed with the first secret")
+	_, _, err = before.Verify(fresh)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	// Once the old secret is dropped, its tokens are rejected
+	_, _, err = NewVerifier([][]byte{newSecret}, "email", time.Hour).Verify(issued)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
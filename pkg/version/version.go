@@ -0,0 +1,30 @@
+package version
+
+import "runtime"
+
+// Build information, injected at build time with
+//
+//	-ldflags "-X github.com/rkgcloud/crud/pkg/version.Version=... -X ...GitSHA=... -X ...BuildDate=..."
+var (
+	Version   = "dev"
+	GitSHA    = "unknown"
+	BuildDate = "unknown"
+)
+
+// Info describes the running build
+type Info struct {
+	Version   string `json:"version"`
+	GitSHA    string `json:"git_sha"`
+	BuildDate string `json:"build_date"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns the build information of the running binary
+func Get() Info {
+	return Info{
+		Version:   Version,
+		GitSHA:    GitSHA,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+	}
+}